@@ -0,0 +1,273 @@
+package valkyrie
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ConfigKeyEnv holds the base64 encoded 32 byte key for encrypted config values.
+	ConfigKeyEnv = "VALKYRIE_CONFIG_KEY"
+	// ConfigKeyFileEnv points to a file holding the key when ConfigKeyEnv is empty.
+	ConfigKeyFileEnv = "VALKYRIE_CONFIG_KEY_FILE"
+
+	encPrefix    = "enc:v1:"
+	encKeyPrefix = "enc:v2:"
+)
+
+var (
+	// ConfigKeyMissing is returned when neither ConfigKeyEnv nor ConfigKeyFileEnv is set.
+	ConfigKeyMissing = errors.New("config key not provided")
+	// ConfigKeyInvalid is returned for a key that is not 32 bytes of base64.
+	ConfigKeyInvalid = errors.New("config key must be 32 bytes")
+	// EncValueInvalid is wrapped by the errors of values that cannot be decrypted.
+	EncValueInvalid = errors.New("invalid encrypted config value")
+)
+
+// ConfigCipher encrypts and decrypts config values with AES-256-GCM,
+// encrypted values look like enc:v1:<base64 nonce and ciphertext>. Values
+// of EncryptKey look like enc:v2:..., they are bound to their key path and
+// cannot be moved to another key.
+type ConfigCipher struct {
+	aead cipher.AEAD
+}
+
+// NewConfigCipher creates a cipher from a 32 byte key.
+func NewConfigCipher(key []byte) (*ConfigCipher, error) {
+	if len(key) != 32 {
+		return nil, ConfigKeyInvalid
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigCipher{aead: aead}, nil
+}
+
+// ConfigCipherFromEnv creates a cipher from the base64 key in ConfigKeyEnv,
+// or from the file named by ConfigKeyFileEnv.
+func ConfigCipherFromEnv() (*ConfigCipher, error) {
+	encoded := os.Getenv(ConfigKeyEnv)
+	if encoded == "" {
+		fp := os.Getenv(ConfigKeyFileEnv)
+		if fp == "" {
+			return nil, fmt.Errorf("%w: set %s or %s", ConfigKeyMissing, ConfigKeyEnv, ConfigKeyFileEnv)
+		}
+		b, err := os.ReadFile(fp)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(b)
+	}
+	key, err := Base64Decode(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ConfigKeyInvalid, err)
+	}
+	return NewConfigCipher(key)
+}
+
+// GenerateConfigKey returns a random base64 encoded key for ConfigKeyEnv.
+func GenerateConfigKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return Base64Encode(key), nil
+}
+
+// IsEncrypted reports whether value is an enc:v1 or enc:v2 config value.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encPrefix) || strings.HasPrefix(value, encKeyPrefix)
+}
+
+// Encrypt returns plain as an enc:v1 config value.
+func (c *ConfigCipher) Encrypt(plain string) (string, error) {
+	return c.seal(encPrefix, plain, nil)
+}
+
+// EncryptKey returns plain as an enc:v2 config value bound to the dot
+// separated key path, e.g. "App.secret_key", matched case-insensitively.
+func (c *ConfigCipher) EncryptKey(key, plain string) (string, error) {
+	return c.seal(encKeyPrefix, plain, keyData(key))
+}
+
+// Decrypt returns the plain text of an enc:v1 config value.
+func (c *ConfigCipher) Decrypt(value string) (string, error) {
+	if strings.HasPrefix(value, encKeyPrefix) {
+		return "", fmt.Errorf("%w: enc:v2 values need their key path, see DecryptKey", EncValueInvalid)
+	}
+	return c.open(encPrefix, value, nil)
+}
+
+// DecryptKey returns the plain text of an enc:v1 config value, or of an
+// enc:v2 config value encrypted for the key path.
+func (c *ConfigCipher) DecryptKey(key, value string) (string, error) {
+	if strings.HasPrefix(value, encKeyPrefix) {
+		return c.open(encKeyPrefix, value, keyData(key))
+	}
+	return c.open(encPrefix, value, nil)
+}
+
+// Resolve implements SecretResolver for the "enc" scheme, ref is "v1:...".
+func (c *ConfigCipher) Resolve(ref string) (string, error) {
+	return c.Decrypt("enc:" + ref)
+}
+
+// ResolveKey implements KeySecretResolver for the "enc" scheme, ref is "v1:..." or "v2:...".
+func (c *ConfigCipher) ResolveKey(key, ref string) (string, error) {
+	return c.DecryptKey(key, "enc:"+ref)
+}
+
+func (c *ConfigCipher) seal(prefix, plain string, data []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plain), data)
+	return prefix + Base64Encode(sealed), nil
+}
+
+func (c *ConfigCipher) open(prefix, value string, data []byte) (string, error) {
+	if !strings.HasPrefix(value, prefix) {
+		return "", EncValueInvalid
+	}
+	sealed, err := Base64Decode(strings.TrimPrefix(value, prefix))
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", EncValueInvalid
+	}
+	n := c.aead.NonceSize()
+	plain, err := c.aead.Open(nil, sealed[:n], sealed[n:], data)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", EncValueInvalid, err)
+	}
+	return string(plain), nil
+}
+
+// keyData is the additional data binding an enc:v2 value to its key path.
+func keyData(key string) []byte {
+	return []byte(strings.ToLower(key))
+}
+
+// EncryptYAML encrypts the values at the given dot separated key paths
+// (e.g. "App.secret_key") of a YAML document as enc:v2 values, keeping comments
+// and layout. Values that are already encrypted are left untouched. The sealed
+// value keeps the quoting style and explicit tag of the plain value, so DecryptYAML
+// gives back "0123" and 'true' as strings.
+func (c *ConfigCipher) EncryptYAML(data []byte, keys ...string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		node := yamlLookup(&doc, strings.Split(key, "."))
+		if node == nil || node.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("key %s not found or not a scalar", key)
+		}
+		if IsEncrypted(node.Value) {
+			continue
+		}
+		v, err := c.EncryptKey(key, node.Value)
+		if err != nil {
+			return nil, err
+		}
+		node.Value = v
+		if node.Style&yaml.TaggedStyle == 0 {
+			node.Tag = "!!str"
+		}
+	}
+	return encodeYAML(&doc)
+}
+
+// DecryptYAML decrypts every enc:v1 and enc:v2 value of a YAML document.
+func (c *ConfigCipher) DecryptYAML(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var walk func(n *yaml.Node, path string) error
+	walk = func(n *yaml.Node, path string) error {
+		switch n.Kind {
+		case yaml.ScalarNode:
+			if !IsEncrypted(n.Value) {
+				return nil
+			}
+			v, err := c.DecryptKey(path, n.Value)
+			if err != nil {
+				return fmt.Errorf("key %s: %w", path, err)
+			}
+			n.Value = v
+			if n.Style&yaml.TaggedStyle == 0 {
+				// the quoting style of the sealed value decides the type again
+				n.Tag = ""
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if err := walk(n.Content[i+1], joinKey(path, n.Content[i].Value)); err != nil {
+					return err
+				}
+			}
+		case yaml.SequenceNode:
+			for i, child := range n.Content {
+				if err := walk(child, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		case yaml.DocumentNode:
+			for _, child := range n.Content {
+				if err := walk(child, path); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(&doc, ""); err != nil {
+		return nil, err
+	}
+	return encodeYAML(&doc)
+}
+
+// yamlLookup returns the value node at path, matching keys case-insensitively.
+func yamlLookup(n *yaml.Node, path []string) *yaml.Node {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		return yamlLookup(n.Content[0], path)
+	}
+	if len(path) == 0 {
+		return n
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if strings.EqualFold(n.Content[i].Value, path[0]) {
+			return yamlLookup(n.Content[i+1], path[1:])
+		}
+	}
+	return nil
+}
+
+func encodeYAML(doc *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
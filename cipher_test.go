@@ -0,0 +1,172 @@
+package valkyrie
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func testCipher(t *testing.T) (*ConfigCipher, string) {
+	t.Helper()
+	key, err := GenerateConfigKey()
+	assert.NoError(t, err)
+	t.Setenv(ConfigKeyEnv, key)
+	c, err := ConfigCipherFromEnv()
+	assert.NoError(t, err)
+	return c, key
+}
+
+func TestConfigCipher(t *testing.T) {
+	c, _ := testCipher(t)
+
+	enc, err := c.Encrypt("sekret")
+	assert.NoError(t, err)
+	assert.True(t, IsEncrypted(enc))
+
+	plain, err := c.Decrypt(enc)
+	assert.NoError(t, err)
+	assert.Equal(t, "sekret", plain)
+
+	other, _ := testCipher(t)
+	_, err = other.Decrypt(enc)
+	assert.True(t, errors.Is(err, EncValueInvalid))
+
+	_, err = NewConfigCipher([]byte("short"))
+	assert.Equal(t, ConfigKeyInvalid, err)
+}
+
+func TestConfigCipherKey(t *testing.T) {
+	c, _ := testCipher(t)
+
+	enc, err := c.EncryptKey("App.secret_key", "sekret")
+	assert.NoError(t, err)
+	assert.True(t, IsEncrypted(enc))
+
+	plain, err := c.DecryptKey("app.SECRET_KEY", enc)
+	assert.NoError(t, err)
+	assert.Equal(t, "sekret", plain)
+
+	// the value cannot be moved to another key
+	_, err = c.DecryptKey("Database.password", enc)
+	assert.True(t, errors.Is(err, EncValueInvalid))
+	_, err = c.Decrypt(enc)
+	assert.True(t, errors.Is(err, EncValueInvalid))
+
+	v1, err := c.Encrypt("sekret")
+	assert.NoError(t, err)
+	plain, err = c.DecryptKey("Database.password", v1)
+	assert.NoError(t, err)
+	assert.Equal(t, "sekret", plain)
+}
+
+func TestConfigCipherKeyFile(t *testing.T) {
+	key, err := GenerateConfigKey()
+	assert.NoError(t, err)
+	fp := writeConfig(t, t.TempDir(), "config.key", key+"\n")
+	t.Setenv(ConfigKeyEnv, "")
+	t.Setenv(ConfigKeyFileEnv, fp)
+
+	_, err = ConfigCipherFromEnv()
+	assert.NoError(t, err)
+
+	t.Setenv(ConfigKeyFileEnv, "")
+	_, err = ConfigCipherFromEnv()
+	assert.True(t, errors.Is(err, ConfigKeyMissing))
+}
+
+func TestConfigCipherYAML(t *testing.T) {
+	c, _ := testCipher(t)
+	src := []byte("App:\n  name: laugh-tale # service name\n  secret_key: sekret\n")
+
+	enc, err := c.EncryptYAML(src, "App.secret_key")
+	assert.NoError(t, err)
+	assert.Contains(t, string(enc), "# service name")
+
+	var out secretConfig
+	assert.NoError(t, yaml.Unmarshal(enc, &out))
+	assert.True(t, IsEncrypted(out.App.SecretKey))
+	plain, err := c.DecryptKey("App.secret_key", out.App.SecretKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "sekret", plain)
+
+	again, err := c.EncryptYAML(enc, "App.secret_key")
+	assert.NoError(t, err)
+	assert.Equal(t, string(enc), string(again))
+
+	dec, err := c.DecryptYAML(enc)
+	assert.NoError(t, err)
+	assert.Equal(t, string(src), string(dec))
+
+	_, err = c.EncryptYAML(src, "App.missing")
+	assert.Error(t, err)
+}
+
+func TestConfigCipherYAMLTypes(t *testing.T) {
+	c, _ := testCipher(t)
+	src := []byte("App:\n  code: \"0123\"\n  flag: 'true'\n  port: 8080\n  tagged: !!str 42\n  key: |\n    line one\n    line two\n")
+	keys := []string{"App.code", "App.flag", "App.port", "App.tagged", "App.key"}
+
+	enc, err := c.EncryptYAML(src, keys...)
+	assert.NoError(t, err)
+	var sealed map[string]map[string]interface{}
+	assert.NoError(t, yaml.Unmarshal(enc, &sealed))
+	for _, v := range sealed["App"] {
+		assert.True(t, IsEncrypted(v.(string)), v)
+	}
+
+	dec, err := c.DecryptYAML(enc)
+	assert.NoError(t, err)
+	assert.Equal(t, string(src), string(dec))
+
+	var out map[string]map[string]interface{}
+	assert.NoError(t, yaml.Unmarshal(dec, &out))
+	assert.Equal(t, map[string]interface{}{
+		"code":   "0123",
+		"flag":   "true",
+		"port":   8080,
+		"tagged": "42",
+		"key":    "line one\nline two\n",
+	}, out["App"])
+}
+
+func TestConfigEncrypted(t *testing.T) {
+	c, _ := testCipher(t)
+	enc, err := c.Encrypt("sekret")
+	assert.NoError(t, err)
+	dir := t.TempDir()
+	writeConfig(t, dir, "app.yaml", "App:\n  secret_key: "+enc+"\n")
+
+	var cfg secretConfig
+	err = Config(ConfigOpts{
+		Config:         &cfg,
+		Filenames:      []string{"app.yaml"},
+		Paths:          []string{dir},
+		ResolveSecrets: true,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "sekret", cfg.App.SecretKey)
+}
+
+func TestConfigEncryptedKey(t *testing.T) {
+	c, _ := testCipher(t)
+	dir := t.TempDir()
+	enc, err := c.EncryptYAML([]byte("App:\n  name: laugh-tale\n  secret_key: sekret\n"), "App.secret_key")
+	assert.NoError(t, err)
+	writeConfig(t, dir, "app.yaml", string(enc))
+
+	var cfg secretConfig
+	opts := ConfigOpts{Config: &cfg, Filenames: []string{"app.yaml"}, Paths: []string{dir}, ResolveSecrets: true}
+	assert.NoError(t, Config(opts))
+	assert.Equal(t, "sekret", cfg.App.SecretKey)
+
+	// moved to another key, the value does not decrypt
+	moved, err := c.EncryptKey("App.name", "sekret")
+	assert.NoError(t, err)
+	writeConfig(t, dir, "app.yaml", "App:\n  secret_key: "+moved+"\n")
+	cfg = secretConfig{}
+	err = Config(opts)
+	assert.True(t, errors.Is(err, EncValueInvalid))
+}
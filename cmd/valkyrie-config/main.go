@@ -0,0 +1,114 @@
+// Command valkyrie-config encrypts and decrypts config values for valkyrie.Config,
+// which decrypts them when ConfigOpts.ResolveSecrets is set.
+//
+// The key is read from VALKYRIE_CONFIG_KEY or the file named by VALKYRIE_CONFIG_KEY_FILE.
+//
+//	valkyrie-config keygen
+//	valkyrie-config encrypt <value> [App.secret_key]
+//	valkyrie-config decrypt <enc:...> [App.secret_key]
+//	valkyrie-config encrypt-file [-w] <app.yaml> <App.secret_key> [key ...]
+//	valkyrie-config decrypt-file [-w] <app.yaml>
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kubuskotak/valkyrie"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "valkyrie-config:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `usage:
+  valkyrie-config keygen
+  valkyrie-config encrypt <value> [key.path]
+  valkyrie-config decrypt <enc:...> [key.path]
+  valkyrie-config encrypt-file [-w] <file.yaml> <key.path> [key.path ...]
+  valkyrie-config decrypt-file [-w] <file.yaml>
+`)
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		usage()
+		return flag.ErrHelp
+	}
+	cmd, args := args[0], args[1:]
+	if cmd == "keygen" {
+		key, err := valkyrie.GenerateConfigKey()
+		if err != nil {
+			return err
+		}
+		fmt.Println(key)
+		return nil
+	}
+
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	write := fs.Bool("w", false, "write the result back to the file instead of stdout")
+	fs.Usage = usage
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+
+	c, err := valkyrie.ConfigCipherFromEnv()
+	if err != nil {
+		return err
+	}
+
+	var out string
+	switch {
+	case cmd == "encrypt" && len(args) == 1:
+		out, err = c.Encrypt(args[0])
+	case cmd == "encrypt" && len(args) == 2:
+		out, err = c.EncryptKey(args[1], args[0])
+	case cmd == "decrypt" && len(args) == 1:
+		out, err = c.Decrypt(args[0])
+	case cmd == "decrypt" && len(args) == 2:
+		out, err = c.DecryptKey(args[1], args[0])
+	case cmd == "encrypt-file" && len(args) >= 2:
+		return rewrite(args[0], *write, func(b []byte) ([]byte, error) {
+			return c.EncryptYAML(b, args[1:]...)
+		})
+	case cmd == "decrypt-file" && len(args) == 1:
+		return rewrite(args[0], *write, c.DecryptYAML)
+	default:
+		usage()
+		return flag.ErrHelp
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(out)
+	return nil
+}
+
+func rewrite(fp string, write bool, fn func([]byte) ([]byte, error)) error {
+	b, err := os.ReadFile(fp)
+	if err != nil {
+		return err
+	}
+	if b, err = fn(b); err != nil {
+		return err
+	}
+	if !write {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	info, err := os.Stat(fp)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fp, b, info.Mode())
+}
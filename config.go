@@ -1,41 +1,529 @@
 package valkyrie
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
 
+	"github.com/BurntSushi/toml"
+	"github.com/go-playground/validator/v10"
 	"github.com/ilyakaznacheev/cleanenv"
+	"gopkg.in/yaml.v3"
+	"olympos.io/encoding/edn"
+)
+
+var (
+	// ConfigNotFound is returned when a filename is not present in any of the search paths.
+	ConfigNotFound = errors.New("config file not found")
+
+	// configValidator reports config keys by their yaml path.
+	configValidator = NewValidator(WithTagNameFunc(configTagName))
+)
+
+const (
+	// ConfigFirstFound loads each filename from the first path that contains it.
+	ConfigFirstFound ConfigMode = iota
+	// ConfigLayered loads each filename from every path that contains it,
+	// values from later files override values from earlier ones.
+	ConfigLayered
 )
 
 type (
+	// ConfigMode decides how the Paths are searched for each of the Filenames.
+	ConfigMode int
+
 	ConfigOpts struct {
 		Config    interface{}
 		Paths     []string
 		Filenames []string
+		Mode      ConfigMode
+
+		// Env selects the overlay loaded on top of each filename,
+		// e.g. Env "production" loads app.production.yaml after app.yaml.
+		Env string
+		// EnvVar names the environment variable read when Env is empty.
+		EnvVar string
+		// EnvField is the key path (e.g. "App.env") read from the loaded
+		// files when neither Env nor EnvVar select an environment.
+		EnvField string
+
+		// Dotenv names the dotenv files read from each path, defaults to DotenvFiles.
+		// Their variables bind to the env tags like environment variables do.
+		Dotenv []string
+		// DurationUnit is the unit of the Duration fields given as plain numbers,
+		// e.g. 40000 is 40s with time.Millisecond, the package DurationUnit when 0.
+		DurationUnit time.Duration
+
+		// DotenvSetenv also sets the dotenv variables in the process environment,
+		// so env secret references and other readers of the environment see them.
+		// Later loads, such as ConfigWatcher.Reload, still read them from the dotenv
+		// files, unless they were changed in the environment since.
+		DotenvSetenv bool
+
+		// ResolveSecrets replaces the secret references of the loaded values, see
+		// ResolveSecrets. It is off by default so values such as the sqlite DSN
+		// file:test.db are kept, setting Secrets turns it on as well.
+		ResolveSecrets bool
+		// Secrets adds resolvers by scheme to the built-in env, file, base64 and enc
+		// references, e.g. "vault" resolves values such as "vault:db/password".
+		Secrets map[string]SecretResolver
+
+		// Sources are applied in order after the files, later sources
+		// override earlier ones and environment variables override them all.
+		Sources []Source
+
+		// Flags set on the command line override every other value.
+		Flags *ConfigFlags
+	}
+
+	// ConfigReport describes what LoadConfig actually loaded.
+	ConfigReport struct {
+		// Files lists the config files in the order they were applied.
+		Files []string
+		// Includes lists the files included by the config files.
+		Includes []string
+		// Dotenv lists the dotenv files in the order they were read.
+		Dotenv []string
+		// Env is the environment used to select overlays.
+		Env string
+		// Sources lists the names of the applied Sources in order.
+		Sources []string
+		// Origins maps every key path (e.g. "App.port") to where its value came from:
+		// a file path, a source name, "env NAME", "env NAME (.env)", "flag --app.port" or "default".
+		Origins map[string]string
+
+		// dotenv maps the dotenv variables missing from the process environment to their file
+		dotenv       map[string]string
+		dotenvOrigin map[string]string
+		durationUnit time.Duration
+	}
+
+	// ConfigError is returned by Config when the loaded values break the validate struct tags.
+	ConfigError struct {
+		Errors []ConfigFieldError `json:"errors"`
+	}
+
+	// ConfigFieldError is a validation error of a single config key.
+	ConfigFieldError struct {
+		ErrorValidator
+		// Key is the key path of the value, e.g. "App.port".
+		Key string `json:"key"`
+		// Source is the file the value came from, empty when no file set it.
+		Source string `json:"source,omitempty"`
 	}
 )
 
-func Config(opts ConfigOpts) error {
-	for _, p := range opts.Paths {
-		fp := filepath.Join(p, ".env")
-		// load env from file
-		if _, fileErr := os.Stat(fp); fileErr == nil {
-			// Set ENV for development
-			_ = cleanenv.ReadConfig(fp, opts.Config)
+func (e *ConfigError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msg := fmt.Sprintf("%s failed on %s", fe.Key, fe.Tag)
+		if fe.Source != "" {
+			msg += " (" + fe.Source + ")"
 		}
+		msgs = append(msgs, msg)
+	}
+	return "config validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Config loads the configuration described by opts into opts.Config.
+func Config(opts ConfigOpts) error {
+	_, err := LoadConfig(opts)
+	return err
+}
+
+// LoadConfig works like Config and reports which files were loaded.
+//
+// Values are applied in increasing order of precedence:
+//
+//	config files < Sources < .env < .env.local < process environment < Flags
+//
+// Every file is decoded on top of the previous ones, so nested structs are
+// deep-merged while slices and scalars are replaced. Sources are applied
+// the same way after the files. Environment variables are applied once
+// after all files and sources have been read, then the command-line Flags,
+// followed by resolving secret references when opted in (see ConfigOpts.ResolveSecrets). Finally the
+// validate struct tags are checked and violations are returned as *ConfigError.
+// opts.Config must be a non-nil pointer, NotPointer or NilPointer is returned otherwise.
+func LoadConfig(opts ConfigOpts) (*ConfigReport, error) {
+	v := reflect.ValueOf(opts.Config)
+	if v.Kind() != reflect.Ptr {
+		return nil, NotPointer
+	}
+	if v.IsNil() {
+		return nil, NilPointer
+	}
+	paths := opts.Paths
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	report := &ConfigReport{Origins: make(map[string]string), durationUnit: opts.DurationUnit}
+	if err := report.loadDotenv(paths, opts); err != nil {
+		return report, err
 	}
-	var err error
 	for _, f := range opts.Filenames {
-		for _, p := range opts.Paths {
-			fp := filepath.Join(p, f)
-			if _, fileErr := os.Stat(fp); fileErr != nil {
-				return fileErr
+		files := lookupConfig(paths, f, opts.Mode)
+		if len(files) == 0 {
+			return report, fmt.Errorf("%w: %s in %s", ConfigNotFound, f, strings.Join(paths, ", "))
+		}
+		if err := report.load(opts.Config, files); err != nil {
+			return report, err
+		}
+	}
+
+	report.Env = configEnv(opts, report.lookupEnv)
+	if report.Env != "" {
+		for _, f := range opts.Filenames {
+			overlay := overlayName(f, report.Env)
+			if err := report.load(opts.Config, lookupConfig(paths, overlay, opts.Mode)); err != nil {
+				return report, err
 			}
-			if err = cleanenv.ReadConfig(fp, opts.Config); err != nil {
+		}
+	}
+
+	for _, src := range opts.Sources {
+		tree, err := src.Load()
+		if err != nil {
+			return report, fmt.Errorf("config source %s: %w", src.Name(), err)
+		}
+		err = applyTree(reflect.ValueOf(opts.Config), tree, "", func(key string) {
+			report.Origins[key] = src.Name()
+		})
+		if err == nil {
+			err = report.applyDurations(opts.Config, tree)
+		}
+		if err != nil {
+			return report, err
+		}
+		report.Sources = append(report.Sources, src.Name())
+	}
+
+	envValues, err := report.applyEnv(opts.Config)
+	if err != nil {
+		return report, err
+	}
+	if err := cleanenv.ReadEnv(opts.Config); err != nil {
+		return report, err
+	}
+	if err := report.applyDurations(opts.Config, expandKeys(envValues)); err != nil {
+		return report, err
+	}
+	if opts.Flags != nil {
+		if err := opts.Flags.apply(opts.Config, report); err != nil {
+			return report, err
+		}
+	}
+	if opts.ResolveSecrets || len(opts.Secrets) > 0 {
+		if err := ResolveSecrets(opts.Config, opts.Secrets); err != nil {
+			return report, err
+		}
+	}
+	return report, validateConfig(opts.Config, report.Origins)
+}
+
+func (r *ConfigReport) load(cfg interface{}, files []string) error {
+	for _, fp := range files {
+		decode, includes, err := readConfigFile(fp, r.lookupEnv)
+		if err != nil {
+			return err
+		}
+		if err = decode(cfg); err != nil {
+			return fmt.Errorf("config file %s parsing error: %w", fp, err)
+		}
+		var tree map[string]interface{}
+		if decode(&tree) == nil {
+			for _, key := range flattenKeys(tree, "") {
+				r.Origins[key] = fp
+			}
+			if err = r.applyDurations(cfg, tree); err != nil {
+				return fmt.Errorf("config file %s parsing error: %w", fp, err)
+			}
+		}
+		r.Files = append(r.Files, fp)
+		r.Includes = append(r.Includes, includes...)
+	}
+	return nil
+}
+
+// loadDotenv reads the dotenv files of every path, all the .env files before
+// all the .env.local files. Variables set in the process environment are ignored.
+func (r *ConfigReport) loadDotenv(paths []string, opts ConfigOpts) error {
+	names := opts.Dotenv
+	if len(names) == 0 {
+		names = DotenvFiles
+	}
+	vars := make(map[string]string)
+	var files []string
+	r.dotenvOrigin = make(map[string]string)
+	for _, name := range names {
+		for _, p := range paths {
+			fp := filepath.Join(p, name)
+			files = append(files, fp)
+			set, err := readDotenvFile(fp, vars)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
 				return err
 			}
+			for _, env := range set {
+				r.dotenvOrigin[env] = fp
+			}
+			r.Dotenv = append(r.Dotenv, fp)
+		}
+	}
+	r.dotenv = make(map[string]string)
+	for env, v := range vars {
+		if _, ok := processEnv(env); ok {
+			delete(r.dotenvOrigin, env)
+			continue
+		}
+		r.dotenv[env] = v
+	}
+	if opts.DotenvSetenv {
+		return setDotenv(r.dotenv, r.dotenvOrigin, files)
+	}
+	return nil
+}
+
+// lookupEnv looks name up in the process environment, then in the dotenv files.
+func (r *ConfigReport) lookupEnv(name string) (string, bool) {
+	if v, ok := processEnv(name); ok {
+		return v, true
+	}
+	v, ok := r.dotenv[name]
+	return v, ok
+}
+
+// applyEnv sets the keys bound to dotenv variables and records the keys
+// cleanenv.ReadEnv is about to set, from an environment variable or from
+// the env-default tag of an empty field. It returns the values by key.
+func (r *ConfigReport) applyEnv(cfg interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, meta := range configMetas(reflect.TypeOf(cfg), "", "") {
+		found := false
+		for _, env := range meta.Env {
+			if v, ok := processEnv(env); ok && r.dotenvOrigin[env] == "" {
+				r.Origins[meta.Key], found = "env "+env, true
+				values[meta.Key] = v
+				break
+			}
+		}
+		for _, env := range meta.Env {
+			if found {
+				break
+			}
+			if v, ok := r.dotenv[env]; ok {
+				fv, ok := configField(cfg, meta.Key)
+				if !ok {
+					break
+				}
+				if err := setConfigValue(fv, v); err != nil {
+					return nil, fmt.Errorf("config %s from %s: %w", meta.Key, env, err)
+				}
+				r.Origins[meta.Key], found = fmt.Sprintf("env %s (%s)", env, r.dotenvOrigin[env]), true
+				values[meta.Key] = v
+			}
+		}
+		if !found && meta.HasDefault {
+			if v, ok := configField(cfg, meta.Key); !ok || v.IsZero() {
+				r.Origins[meta.Key] = "default"
+				values[meta.Key] = meta.Default
+			}
+		}
+	}
+	return values, nil
+}
+
+// applyDurations sets the Duration fields given as plain numbers in tree
+// in the DurationUnit of the config, when it has one.
+func (r *ConfigReport) applyDurations(cfg interface{}, tree map[string]interface{}) error {
+	if r.durationUnit == 0 {
+		return nil
+	}
+	return applyDurationTree(reflect.ValueOf(cfg), tree, "", r.durationUnit)
+}
+
+// flattenKeys returns the key paths of every leaf value in tree.
+func flattenKeys(tree map[string]interface{}, prefix string) []string {
+	var keys []string
+	for k, v := range tree {
+		if sub, ok := v.(map[string]interface{}); ok && len(sub) > 0 {
+			keys = append(keys, flattenKeys(sub, joinKey(prefix, k))...)
+			continue
 		}
+		keys = append(keys, joinKey(prefix, k))
 	}
+	return keys
+}
 
+// validateConfig checks the validate struct tags of cfg, reporting keys by their yaml path.
+func validateConfig(cfg interface{}, origins map[string]string) error {
+	err := configValidator.validate.Struct(cfg)
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	cerr := &ConfigError{}
+	for _, ev := range toErrorValidators(verrs, reflect.TypeOf(cfg), configValidator.locale) {
+		cerr.Errors = append(cerr.Errors, ConfigFieldError{
+			ErrorValidator: ev,
+			Key:            ev.Path,
+			Source:         origins[ev.Path],
+		})
+	}
+	return cerr
+}
+
+func configTagName(fld reflect.StructField) string {
+	if name := configKey(fld); name != "-" {
+		return name
+	}
+	return ""
+}
+
+// lookupConfig returns the existing files named f within paths according to mode.
+func lookupConfig(paths []string, f string, mode ConfigMode) []string {
+	var files []string
+	for _, p := range paths {
+		fp := filepath.Join(p, f)
+		if info, err := os.Stat(fp); err != nil || info.IsDir() {
+			continue
+		}
+		files = append(files, fp)
+		if mode == ConfigFirstFound {
+			break
+		}
+	}
+	return files
+}
+
+// configEnv resolves the overlay environment from opts in order of precedence.
+func configEnv(opts ConfigOpts, lookupEnv func(string) (string, bool)) string {
+	if opts.Env != "" {
+		return opts.Env
+	}
+	if opts.EnvVar != "" {
+		if env, _ := lookupEnv(opts.EnvVar); env != "" {
+			return env
+		}
+	}
+	if opts.EnvField != "" {
+		if v, ok := configField(opts.Config, opts.EnvField); ok && v.Kind() == reflect.String {
+			return v.String()
+		}
+	}
+	return ""
+}
+
+// overlayName inserts env before the extension, app.yaml becomes app.<env>.yaml.
+func overlayName(f, env string) string {
+	ext := filepath.Ext(f)
+	return strings.TrimSuffix(f, ext) + "." + env + ext
+}
+
+// configField finds the field addressed by a dot separated key path,
+// matching each segment case-insensitively against the yaml tag or field name.
+func configField(cfg interface{}, path string) (reflect.Value, bool) {
+	v := reflect.ValueOf(cfg)
+	for _, key := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			if strings.EqualFold(configKey(v.Type().Field(i)), key) {
+				v, found = v.Field(i), true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
+
+// configKey returns the key a struct field is decoded from,
+// untagged fields use the lowercased field name like yaml does.
+func configKey(fld reflect.StructField) string {
+	name := strings.SplitN(fld.Tag.Get("yaml"), ",", 2)[0]
+	if name == "" {
+		return strings.ToLower(fld.Name)
+	}
+	return name
+}
+
+// configMeta describes a leaf config key of a struct type.
+type configMeta struct {
+	// Key is the dot separated key path, e.g. "App.port".
+	Key        string
+	Env        []string
+	Default    string
+	HasDefault bool
+	Field      reflect.StructField
+}
+
+// configMetas lists the leaf keys of t in field order, following
+// the env and env-prefix tags the same way cleanenv does.
+func configMetas(t reflect.Type, prefix, envPrefix string) []configMeta {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var metas []configMeta
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		name := configKey(fld)
+		if fld.PkgPath != "" || name == "-" {
+			continue
+		}
+		key := joinKey(prefix, name)
+		if isConfigStruct(fld.Type) {
+			metas = append(metas, configMetas(fld.Type, key, envPrefix+fld.Tag.Get(cleanenv.TagEnvPrefix))...)
+			continue
+		}
+		meta := configMeta{Key: key, Field: fld}
+		if envs := fld.Tag.Get(cleanenv.TagEnv); envs != "" {
+			for _, env := range strings.Split(envs, cleanenv.DefaultSeparator) {
+				meta.Env = append(meta.Env, envPrefix+env)
+			}
+		}
+		meta.Default, meta.HasDefault = fld.Tag.Lookup(cleanenv.TagEnvDefault)
+		metas = append(metas, meta)
+	}
+	return metas
+}
+
+func decodeConfig(r io.Reader, ext string, cfg interface{}) error {
+	var err error
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		err = yaml.NewDecoder(r).Decode(cfg)
+		if err == io.EOF {
+			err = nil
+		}
+	case ".json":
+		err = json.NewDecoder(r).Decode(cfg)
+	case ".toml":
+		_, err = toml.DecodeReader(r, cfg)
+	case ".edn":
+		err = edn.NewDecoder(r).Decode(cfg)
+	default:
+		err = fmt.Errorf("file format '%s' is not supported by the parser", ext)
+	}
 	return err
 }
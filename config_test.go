@@ -1,7 +1,9 @@
 package valkyrie
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -10,15 +12,15 @@ import (
 
 type constants struct {
 	App struct {
-		Name         string         `yaml:"name"`
-		Port         int            `yaml:"port"`
-		ReadTimeout  int            `yaml:"read_timeout"`
-		WriteTimeout int            `yaml:"write_timeout"`
-		Timezone     string         `yaml:"timezone"`
-		Debug        bool           `yaml:"debug"`
-		Env          string         `yaml:"env"`
-		SecretKey    string         `yaml:"secret_key"`
-		ExpireIn     *time.Duration `yaml:"expire_in"`
+		Name         string    `yaml:"name"`
+		Port         int       `yaml:"port"`
+		ReadTimeout  int       `yaml:"read_timeout"`
+		WriteTimeout int       `yaml:"write_timeout"`
+		Timezone     Location  `yaml:"timezone"`
+		Debug        bool      `yaml:"debug"`
+		Env          string    `yaml:"env"`
+		SecretKey    string    `yaml:"secret_key"`
+		ExpireIn     *Duration `yaml:"expire_in"`
 	} `yaml:"App"`
 
 	DB struct {
@@ -34,8 +36,10 @@ func TestConfig(t *testing.T) {
 		Paths:     []string{".", "./config"},
 	})
 
-	assert.Error(t, err)
+	assert.NoError(t, err)
 	assert.Equal(t, 8778, cfg.App.Port)
+	assert.Equal(t, 40000*time.Second, cfg.App.ExpireIn.Duration())
+	assert.Equal(t, "Asia/Jakarta", cfg.App.Timezone.Location().String())
 }
 
 func TestConfigPathFail(t *testing.T) {
@@ -47,6 +51,7 @@ func TestConfigPathFail(t *testing.T) {
 	})
 
 	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ConfigNotFound))
 }
 
 func TestConfigEnv(t *testing.T) {
@@ -60,7 +65,7 @@ func TestConfigEnv(t *testing.T) {
 		Paths:     []string{".", "./config"},
 	})
 
-	assert.Error(t, err)
+	assert.NoError(t, err)
 	assert.Equal(t, val, cfg.DB.DsnMain)
 }
 
@@ -76,6 +81,160 @@ func TestConfigFunc(t *testing.T) {
 		Paths:     []string{".", "./config"},
 	})
 
-	assert.Error(t, err)
+	assert.NoError(t, err)
 	assert.Equal(t, val, cfg.DB.DsnMain)
 }
+
+func writeConfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(dir, 0o755))
+	fp := filepath.Join(dir, name)
+	assert.NoError(t, os.WriteFile(fp, []byte(content), 0o644))
+	return fp
+}
+
+func TestConfigFirstFound(t *testing.T) {
+	dir := t.TempDir()
+	base := writeConfig(t, dir, "app.yaml", "App:\n  name: base\n  port: 8000\n")
+	writeConfig(t, filepath.Join(dir, "config"), "app.yaml", "App:\n  port: 9000\n")
+
+	var cfg constants
+	report, err := LoadConfig(ConfigOpts{
+		Config:    &cfg,
+		Filenames: []string{"app.yaml"},
+		Paths:     []string{filepath.Join(dir, "missing"), dir, filepath.Join(dir, "config")},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{base}, report.Files)
+	assert.Equal(t, "base", cfg.App.Name)
+	assert.Equal(t, 8000, cfg.App.Port)
+}
+
+func TestConfigNotPointer(t *testing.T) {
+	var cfg *constants
+	assert.Equal(t, NotPointer, Config(ConfigOpts{}))
+	assert.Equal(t, NotPointer, Config(ConfigOpts{Config: constants{}}))
+	assert.Equal(t, NilPointer, Config(ConfigOpts{Config: cfg}))
+}
+
+func TestConfigLayered(t *testing.T) {
+	dir := t.TempDir()
+	base := writeConfig(t, dir, "app.yaml", "App:\n  name: base\n  port: 8000\n")
+	override := writeConfig(t, filepath.Join(dir, "config"), "app.yaml", "App:\n  port: 9000\n")
+
+	var cfg constants
+	report, err := LoadConfig(ConfigOpts{
+		Config:    &cfg,
+		Filenames: []string{"app.yaml"},
+		Paths:     []string{dir, filepath.Join(dir, "missing"), filepath.Join(dir, "config")},
+		Mode:      ConfigLayered,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{base, override}, report.Files)
+	assert.Equal(t, "base", cfg.App.Name)
+	assert.Equal(t, 9000, cfg.App.Port)
+}
+
+func TestConfigEnvOverlay(t *testing.T) {
+	dir := t.TempDir()
+	base := writeConfig(t, dir, "app.yaml", "App:\n  name: base\n  port: 8000\n  env: production\n  debug: true\n")
+	overlay := writeConfig(t, dir, "app.production.yaml", "App:\n  port: 9000\n  debug: false\n")
+	writeConfig(t, dir, "app.staging.yaml", "App:\n  port: 7000\n")
+
+	t.Run("env field", func(t *testing.T) {
+		var cfg constants
+		report, err := LoadConfig(ConfigOpts{
+			Config:    &cfg,
+			Filenames: []string{"app.yaml"},
+			Paths:     []string{dir},
+			EnvField:  "App.env",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "production", report.Env)
+		assert.Equal(t, []string{base, overlay}, report.Files)
+		assert.Equal(t, "base", cfg.App.Name)
+		assert.Equal(t, 9000, cfg.App.Port)
+		assert.False(t, cfg.App.Debug)
+	})
+
+	t.Run("env var", func(t *testing.T) {
+		t.Setenv("APP_ENV", "staging")
+		var cfg constants
+		report, err := LoadConfig(ConfigOpts{
+			Config:    &cfg,
+			Filenames: []string{"app.yaml"},
+			Paths:     []string{dir},
+			EnvVar:    "APP_ENV",
+			EnvField:  "App.env",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "staging", report.Env)
+		assert.Equal(t, 7000, cfg.App.Port)
+		assert.True(t, cfg.App.Debug)
+	})
+
+	t.Run("missing overlay", func(t *testing.T) {
+		var cfg constants
+		report, err := LoadConfig(ConfigOpts{
+			Config:    &cfg,
+			Filenames: []string{"app.yaml"},
+			Paths:     []string{dir},
+			Env:       "development",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{base}, report.Files)
+		assert.Equal(t, 8000, cfg.App.Port)
+	})
+}
+
+type validatedConfig struct {
+	App struct {
+		Name string `yaml:"name" validate:"required"`
+		Port int    `yaml:"port" validate:"required,gt=1024"`
+		Env  string `yaml:"env" validate:"enum=development staging production"`
+	} `yaml:"App"`
+}
+
+func TestConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+	fp := writeConfig(t, dir, "app.yaml", "App:\n  port: 80\n  env: testing\n")
+
+	var cfg validatedConfig
+	report, err := LoadConfig(ConfigOpts{
+		Config:    &cfg,
+		Filenames: []string{"app.yaml"},
+		Paths:     []string{dir},
+	})
+
+	var cerr *ConfigError
+	assert.True(t, errors.As(err, &cerr))
+	assert.Equal(t, map[string]string{"App.port": fp, "App.env": fp}, report.Origins)
+	assert.Equal(t, []ConfigFieldError{
+		{
+			ErrorValidator: ErrorValidator{Tag: "required", Field: "name", Path: "App.name", Pointer: "/App/name", Type: "string", Message: "name is required"},
+			Key:            "App.name",
+		},
+		{
+			ErrorValidator: ErrorValidator{
+				Tag: "gt", Field: "port", Path: "App.port", Pointer: "/App/port", Value: "80", Param: "1024", Type: "int",
+				Message: "port must be greater than 1024",
+			},
+			Key:    "App.port",
+			Source: fp,
+		},
+		{
+			ErrorValidator: ErrorValidator{
+				Tag: "enum", Field: "env", Path: "App.env", Pointer: "/App/env", Value: "testing", Param: "development staging production", Type: "string",
+				Message: "env must be one of: development, staging, production",
+			},
+			Key:    "App.env",
+			Source: fp,
+		},
+	}, cerr.Errors)
+	assert.Contains(t, err.Error(), "App.port failed on gt ("+fp+")")
+}
@@ -1,17 +1,33 @@
 package valkyrie
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
 	"math/rand"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/oklog/ulid/v2"
 )
 
+// NodeBits is the number of entropy bits reserved for the node ID
+// when a ULID generator is created with WithNode or WithNodeEnv.
+const NodeBits = 16
+
+// NodeIDInvalid is returned for a numeric node ID that does not fit in NodeBits.
+var NodeIDInvalid = errors.New("node ID must be between 0 and 65535")
+
 type safeUlid struct {
 	safe      *safeMonotonicReader
 	t         time.Time
-	monotonic *ulid.MonotonicEntropy
+	monotonic ulid.MonotonicReader
+	node      *uint16
 }
 
 func (s *safeUlid) SafeMonotonic() ulid.ULID {
@@ -22,16 +38,105 @@ func (s *safeUlid) Monotonic() ulid.ULID {
 	return ulid.MustNew(ulid.Timestamp(s.t), s.monotonic)
 }
 
+// Node returns the node ID embedded in every generated ULID,
+// ok is false when the generator was created without a node.
+func (s *safeUlid) Node() (node uint16, ok bool) {
+	if s.node == nil {
+		return 0, false
+	}
+	return *s.node, true
+}
+
+// ULIDOption configures the ULID generator.
+type ULIDOption func(*safeUlid)
+
+// WithNode reserves the first NodeBits of entropy for the given node ID, so ULIDs
+// from instances with different IDs never collide within the same millisecond.
+func WithNode(id uint16) ULIDOption {
+	return func(s *safeUlid) {
+		s.node = &id
+	}
+}
+
+// WithNodeEnv reads the node ID from the environment variable key, see NodeID.
+func WithNodeEnv(key string) (ULIDOption, error) {
+	id, err := NodeID(os.Getenv(key))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return WithNode(id), nil
+}
+
+// ULIDNode extracts the node ID from a ULID generated with WithNode or WithNodeEnv.
+func ULIDNode(id ulid.ULID) uint16 {
+	return binary.BigEndian.Uint16(id[6:8])
+}
+
 // ULID Universally Unique Lexicographically Sortable Identifier
-func ULID(t time.Time) *safeUlid {
+func ULID(t time.Time, opts ...ULIDOption) *safeUlid {
 	src := rand.NewSource(time.Now().UnixNano())
 	entropy := rand.New(src)
-	monotonic := ulid.Monotonic(entropy, 0)
-	return &safeUlid{
-		safe:      &safeMonotonicReader{MonotonicReader: monotonic},
-		t:         t,
-		monotonic: monotonic,
+	s := &safeUlid{t: t}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.node != nil {
+		s.monotonic = &nodeMonotonicReader{node: *s.node, rng: entropy}
+	} else {
+		s.monotonic = ulid.Monotonic(entropy, 0)
+	}
+	s.safe = &safeMonotonicReader{MonotonicReader: s.monotonic}
+	return s
+}
+
+// NodeID returns the node ID of v. Numbers are used as is and must fit in NodeBits,
+// any other value (e.g. a pod name) is hashed, and when v is empty the hostname is
+// hashed instead. Hashed IDs may collide: two of 50 names share an ID with a chance
+// of about 2%, assign numbers to the instances when IDs must be unique.
+func NodeID(v string) (uint16, error) {
+	if v == "" {
+		v, _ = os.Hostname()
+	}
+	if isDigits(strings.TrimPrefix(v, "-")) {
+		n, err := strconv.ParseUint(v, 10, NodeBits)
+		if err != nil {
+			return 0, fmt.Errorf("%w, got %s", NodeIDInvalid, v)
+		}
+		return uint16(n), nil
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(v))
+	return uint16(h.Sum32()), nil
+}
+
+// nodeMonotonicReader writes the node ID into the first two entropy bytes
+// and keeps the remaining 64 bits monotonic within the same millisecond.
+type nodeMonotonicReader struct {
+	node uint16
+	rng  *rand.Rand
+	ms   uint64
+	last uint64
+	used bool
+}
+
+func (r *nodeMonotonicReader) Read(p []byte) (int, error) {
+	return r.rng.Read(p)
+}
+
+func (r *nodeMonotonicReader) MonotonicRead(ms uint64, p []byte) error {
+	if r.used && r.ms == ms {
+		next := r.last + 1 + uint64(r.rng.Int63n(math.MaxUint32))
+		if next <= r.last {
+			return ulid.ErrMonotonicOverflow
+		}
+		r.last = next
+	} else {
+		// clear the top bit to leave headroom for increments
+		r.ms, r.last, r.used = ms, r.rng.Uint64()>>1, true
 	}
+	binary.BigEndian.PutUint16(p[:2], r.node)
+	binary.BigEndian.PutUint64(p[2:], r.last)
+	return nil
 }
 
 type safeMonotonicReader struct {
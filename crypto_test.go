@@ -1,6 +1,7 @@
 package valkyrie
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -65,3 +66,64 @@ func TestMonotonicSafe(t *testing.T) {
 		}
 	}
 }
+
+func TestMonotonicNode(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Now()
+	a := ULID(t0, WithNode(7))
+	b := ULID(t0, WithNode(8))
+	node, ok := a.Node()
+	if !ok || node != 7 {
+		t.Fatalf("node = %d, %v; want 7, true", node, ok)
+	}
+
+	u0 := a.SafeMonotonic()
+	for j := 0; j < 1024; j++ {
+		u1 := a.SafeMonotonic()
+		if u0.String() >= u1.String() {
+			t.Fatalf("%s >= %s", u0.String(), u1.String())
+		}
+		if ULIDNode(u1) != 7 {
+			t.Fatalf("ULIDNode(%s) = %d, want 7", u1.String(), ULIDNode(u1))
+		}
+		u0 = u1
+	}
+	if got := ULIDNode(b.Monotonic()); got != 8 {
+		t.Fatalf("ULIDNode = %d, want 8", got)
+	}
+	if _, ok := ULID(t0).Node(); ok {
+		t.Fatal("expected no node without WithNode")
+	}
+}
+
+func TestMonotonicNodeEnv(t *testing.T) {
+	t.Setenv("VALKYRIE_NODE_ID", "42")
+	opt, err := WithNodeEnv("VALKYRIE_NODE_ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node, _ := ULID(time.Now(), opt).Node(); node != 42 {
+		t.Fatalf("node = %d, want 42", node)
+	}
+
+	t.Setenv("VALKYRIE_NODE_ID", "api-7d9f-abc")
+	hashed, _ := NodeID("api-7d9f-abc")
+	again, _ := NodeID("api-7d9f-abc")
+	if hashed != again {
+		t.Fatalf("hashed node not stable: %d != %d", hashed, again)
+	}
+	if opt, err = WithNodeEnv("VALKYRIE_NODE_ID"); err != nil {
+		t.Fatal(err)
+	}
+	if node, _ := ULID(time.Now(), opt).Node(); node != hashed {
+		t.Fatalf("node = %d, want %d", node, hashed)
+	}
+
+	for _, v := range []string{"70000", "65536", "-1"} {
+		t.Setenv("VALKYRIE_NODE_ID", v)
+		if _, err := WithNodeEnv("VALKYRIE_NODE_ID"); !errors.Is(err, NodeIDInvalid) {
+			t.Fatalf("WithNodeEnv(%s) error = %v, want NodeIDInvalid", v, err)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package valkyrie
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// DateLayout is the layout of the date tag without a parameter.
+	DateLayout = "2006-01-02"
+	// DatetimeLayout is the layout of the datetime tag without a parameter.
+	DatetimeLayout = time.RFC3339
+	// DefaultDateRange bounds the daterange tag without a parameter.
+	DefaultDateRange = "1900-01-01~2100-01-01"
+)
+
+// defaultDateLayouts read the strings checked by the daterange, past, future, age_min,
+// age_max, after_field and before_field tags, after the layouts of the date and datetime
+// tags of the field and those given to WithDateLayouts.
+var defaultDateLayouts = []string{DateLayout, DatetimeLayout}
+
+// dateTags holds the date rules registered by NewValidator:
+//
+//	date=02/01/2006              a string in the layout, DateLayout by default
+//	datetime=2006-01-02 15:04    a string in the layout, DatetimeLayout by default
+//	daterange=2000-01-01~today   between two dates, today or now, DefaultDateRange by default
+//	past, future                 before today or after today, now for values with a time of day
+//	age_min=17, age_max=65       a birth date of someone at least or at most that many years old
+//	after_field=StartDate        after the sibling field StartDate, also before_field
+//
+// The rules accept strings and time.Time, dates are read and compared in the location
+// of the Validator, see WithLocation, so date=02/01/2006,past reads 17/08/1990.
+var dateTags = map[string]func(dateRules, validator.FieldLevel) bool{
+	"date":         dateRules.date,
+	"datetime":     dateRules.datetime,
+	"daterange":    dateRules.dateRange,
+	"past":         dateRules.past,
+	"future":       dateRules.future,
+	"age_min":      dateRules.ageMin,
+	"age_max":      dateRules.ageMax,
+	"after_field":  dateRules.afterField,
+	"before_field": dateRules.beforeField,
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// fieldLayouts caches the date and datetime layouts of struct fields by fieldLayoutsKey.
+var fieldLayouts sync.Map
+
+type (
+	// dateRules implements dateTags in loc, layouts read strings.
+	dateRules struct {
+		loc     *time.Location
+		layouts []string
+	}
+
+	fieldLayoutsKey struct {
+		parent reflect.Type
+		field  string
+	}
+)
+
+// utcDates backs the package-level date rules.
+var utcDates = dateRules{loc: time.UTC, layouts: defaultDateLayouts}
+
+// WithLocation reads and compares dates in loc instead of UTC, so today
+// follows the calendar of the users, e.g. WithLocation(cfg.Timezone.Location()).
+func WithLocation(loc *time.Location) ValidatorOption {
+	return func(v *Validator) {
+		if loc != nil {
+			v.dates.loc = loc
+		}
+	}
+}
+
+// WithDateLayouts reads the strings checked by the date rules with layouts as well,
+// they are tried after the date and datetime layouts of the field.
+func WithDateLayouts(layouts ...string) ValidatorOption {
+	return func(v *Validator) {
+		v.dates.layouts = append(append([]string(nil), layouts...), v.dates.layouts...)
+	}
+}
+
+// DateValidation validates the date tag in UTC.
+func DateValidation(fl validator.FieldLevel) bool {
+	return utcDates.date(fl)
+}
+
+// DatetimeValidation validates the datetime tag in UTC.
+func DatetimeValidation(fl validator.FieldLevel) bool {
+	return utcDates.datetime(fl)
+}
+
+// DateRangeValidation validates the daterange tag in UTC.
+func DateRangeValidation(fl validator.FieldLevel) bool {
+	return utcDates.dateRange(fl)
+}
+
+func ParseDate(dtStr string) time.Time {
+	date, err := time.Parse(DateLayout, dtStr)
+	if err != nil {
+		return time.Time{}
+	}
+	return date
+}
+
+func ParseDatetime(dtStr string) time.Time {
+	dateTime, err := time.Parse(DatetimeLayout, dtStr)
+	if err != nil {
+		return time.Time{}
+	}
+	return dateTime
+}
+
+func (d dateRules) date(fl validator.FieldLevel) bool {
+	_, _, ok := d.parse(fl.Field(), paramOr(fl.Param(), DateLayout))
+	return ok
+}
+
+func (d dateRules) datetime(fl validator.FieldLevel) bool {
+	_, _, ok := d.parse(fl.Field(), paramOr(fl.Param(), DatetimeLayout))
+	return ok
+}
+
+// dateRange checks the calendar day of the value against the bounds, or the
+// instant when a bound is now.
+func (d dateRules) dateRange(fl validator.FieldLevel) bool {
+	bounds := strings.SplitN(paramOr(fl.Param(), DefaultDateRange), "~", 2)
+	if len(bounds) != 2 {
+		return false
+	}
+	t, _, ok := d.parse(fl.Field(), d.fieldLayouts(fl)...)
+	if !ok {
+		return false
+	}
+	min, minNow, ok := d.bound(bounds[0])
+	if !ok {
+		return false
+	}
+	max, maxNow, ok := d.bound(bounds[1])
+	if !ok {
+		return false
+	}
+	if minNow && t.Before(min) || !minNow && d.day(t).Before(min) {
+		return false
+	}
+	return !(maxNow && t.After(max) || !maxNow && d.day(t).After(max))
+}
+
+func (d dateRules) past(fl validator.FieldLevel) bool {
+	t, dateOnly, ok := d.parse(fl.Field(), d.fieldLayouts(fl)...)
+	if !ok {
+		return false
+	}
+	if dateOnly {
+		return t.Before(d.today())
+	}
+	return t.Before(time.Now())
+}
+
+func (d dateRules) future(fl validator.FieldLevel) bool {
+	t, dateOnly, ok := d.parse(fl.Field(), d.fieldLayouts(fl)...)
+	if !ok {
+		return false
+	}
+	if dateOnly {
+		return t.After(d.today())
+	}
+	return t.After(time.Now())
+}
+
+func (d dateRules) ageMin(fl validator.FieldLevel) bool {
+	age, years, ok := d.age(fl)
+	return ok && age >= years
+}
+
+func (d dateRules) ageMax(fl validator.FieldLevel) bool {
+	age, years, ok := d.age(fl)
+	return ok && age <= years
+}
+
+// age returns the age in whole years of someone born on the value and the years of the parameter.
+func (d dateRules) age(fl validator.FieldLevel) (int, int, bool) {
+	years, err := strconv.Atoi(fl.Param())
+	if err != nil {
+		return 0, 0, false
+	}
+	birth, _, ok := d.parse(fl.Field(), d.fieldLayouts(fl)...)
+	if !ok {
+		return 0, 0, false
+	}
+	birth, today := d.day(birth), d.today()
+	age := today.Year() - birth.Year()
+	if today.Month() < birth.Month() || today.Month() == birth.Month() && today.Day() < birth.Day() {
+		age--
+	}
+	return age, years, true
+}
+
+func (d dateRules) afterField(fl validator.FieldLevel) bool {
+	cmp, set, ok := d.compareField(fl)
+	return ok && (!set || cmp > 0)
+}
+
+func (d dateRules) beforeField(fl validator.FieldLevel) bool {
+	cmp, set, ok := d.compareField(fl)
+	return ok && (!set || cmp < 0)
+}
+
+// compareField compares the value with the sibling field named by the parameter, by calendar
+// day when both hold dates only. set is false when the sibling is empty or invalid, it is left
+// to its own rules and the comparison passes.
+func (d dateRules) compareField(fl validator.FieldLevel) (cmp int, set bool, ok bool) {
+	t, dateOnly, ok := d.parse(fl.Field(), d.fieldLayouts(fl)...)
+	if !ok {
+		return 0, false, false
+	}
+	parent := fl.Parent()
+	for parent.Kind() == reflect.Ptr && !parent.IsNil() {
+		parent = parent.Elem()
+	}
+	if parent.Kind() != reflect.Struct {
+		return 0, false, false
+	}
+	field, _, _, found := fl.GetStructFieldOKAdvanced2(parent, fl.Param())
+	if !found {
+		return 0, false, false
+	}
+	other, otherDateOnly, ok := d.parse(field, d.structLayouts(parent, fl.Param())...)
+	if !ok {
+		return 0, false, true
+	}
+	if dateOnly && otherDateOnly {
+		t, other = d.day(t), d.day(other)
+	}
+	switch {
+	case t.After(other):
+		return 1, true, true
+	case t.Before(other):
+		return -1, true, true
+	}
+	return 0, true, true
+}
+
+// parse reads a time.Time, or a string in the first matching layout, in the location
+// of d. dateOnly reports a string whose layout has no time of day.
+func (d dateRules) parse(v reflect.Value, layouts ...string) (t time.Time, dateOnly bool, ok bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return time.Time{}, false, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct && v.Type().ConvertibleTo(timeType) {
+		return v.Convert(timeType).Interface().(time.Time).In(d.loc), false, true
+	}
+	if v.Kind() != reflect.String {
+		return time.Time{}, false, false
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, v.String(), d.loc); err == nil {
+			// 3, 4 and 5 are the hour, minute and second of a layout
+			return t, !strings.ContainsAny(layout, "345"), true
+		}
+	}
+	return time.Time{}, false, false
+}
+
+// bound reads a daterange bound, a date in the layouts of d, today or now.
+func (d dateRules) bound(s string) (t time.Time, now bool, ok bool) {
+	switch strings.TrimSpace(s) {
+	case "today":
+		return d.today(), false, true
+	case "now":
+		return time.Now(), true, true
+	}
+	for _, layout := range d.layouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(s), d.loc); err == nil {
+			return d.day(t), false, true
+		}
+	}
+	return time.Time{}, false, false
+}
+
+// fieldLayouts returns the layouts of the date and datetime tags of the field
+// followed by the layouts of d.
+func (d dateRules) fieldLayouts(fl validator.FieldLevel) []string {
+	// the elements of dive share the tag of their field, e.g. Dates[2]
+	name := fl.StructFieldName()
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name = name[:i]
+	}
+	return d.structLayouts(fl.Parent(), name)
+}
+
+// structLayouts returns the layouts of the date and datetime tags of the field
+// name of parent followed by the layouts of d.
+func (d dateRules) structLayouts(parent reflect.Value, name string) []string {
+	for parent.Kind() == reflect.Ptr && !parent.IsNil() {
+		parent = parent.Elem()
+	}
+	if parent.Kind() != reflect.Struct {
+		return d.layouts
+	}
+	key := fieldLayoutsKey{parent: parent.Type(), field: name}
+	cached, ok := fieldLayouts.Load(key)
+	if !ok {
+		var layouts []string
+		if fld, found := parent.Type().FieldByName(name); found {
+			for _, rule := range strings.Split(fld.Tag.Get("validate"), ",") {
+				parts := strings.SplitN(rule, "=", 2)
+				if len(parts) == 2 && parts[1] != "" && (parts[0] == "date" || parts[0] == "datetime") {
+					layouts = append(layouts, parts[1])
+				}
+			}
+		}
+		cached, _ = fieldLayouts.LoadOrStore(key, layouts)
+	}
+	if layouts := cached.([]string); len(layouts) > 0 {
+		return append(append([]string(nil), layouts...), d.layouts...)
+	}
+	return d.layouts
+}
+
+func (d dateRules) today() time.Time {
+	return d.day(time.Now())
+}
+
+// day returns the start of the calendar day of t in the location of d.
+func (d dateRules) day(t time.Time) time.Time {
+	y, m, day := t.In(d.loc).Date()
+	return time.Date(y, m, day, 0, 0, 0, 0, d.loc)
+}
+
+func paramOr(param, def string) string {
+	if param == "" {
+		return def
+	}
+	return param
+}
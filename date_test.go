@@ -0,0 +1,147 @@
+package valkyrie
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bookingForm struct {
+	Birthday  string     `json:"birthday" validate:"date=02/01/2006"`
+	Arrival   string     `json:"arrival" validate:"datetime=2006-01-02 15:04"`
+	Issued    string     `json:"issued" validate:"daterange=2000-01-01~today"`
+	Born      string     `json:"born" validate:"past,age_min=17,age_max=65"`
+	StartDate time.Time  `json:"start_date" validate:"future"`
+	EndDate   *time.Time `json:"end_date" validate:"omitempty,after_field=StartDate"`
+	CheckIn   string     `json:"check_in" validate:"omitempty,before_field=CheckOut"`
+	CheckOut  string     `json:"check_out"`
+}
+
+func TestDateRules(t *testing.T) {
+	now := time.Now().UTC()
+	day := func(years, days int) string { return now.AddDate(years, 0, days).Format(DateLayout) }
+	start := now.Add(time.Hour)
+	end := start.Add(24 * time.Hour)
+
+	valid := bookingForm{
+		Birthday:  "17/08/1945",
+		Arrival:   "2022-07-26 09:30",
+		Issued:    day(0, 0),
+		Born:      day(-17, 0),
+		StartDate: start,
+		EndDate:   &end,
+		CheckIn:   "2022-07-26",
+		CheckOut:  "2022-07-28T12:00:00+07:00",
+	}
+	assert.Nil(t, Validate(valid))
+
+	before := now.Add(-2 * time.Hour)
+	errs := Validate(bookingForm{
+		Birthday:  "1945-08-17",
+		Arrival:   "2022-07-26T09:30:00Z",
+		Issued:    day(0, 1),
+		Born:      day(-17, 1),
+		StartDate: now.Add(-time.Hour),
+		EndDate:   &before,
+		CheckIn:   "2022-07-28",
+		CheckOut:  "2022-07-28",
+	})
+	assert.Equal(t, []string{
+		"birthday must be a date formatted as DD/MM/YYYY, e.g. 02/01/2006",
+		"arrival must be a date and time formatted as YYYY-MM-DD HH:mm, e.g. 2006-01-02 15:04",
+		"issued must be a date between 2000-01-01 and today",
+		"born must be a birth date of someone at least 17 years old",
+		"start_date must be a date in the future",
+		"end_date must be after StartDate",
+		"check_in must be before CheckOut",
+	}, errorMessages(errs))
+
+	errs = Validate(bookingForm{Birthday: "17/08/1945", Arrival: "2022-07-26 09:30", Issued: "1999-12-31", Born: day(0, 1), StartDate: start})
+	assert.Equal(t, []string{
+		"issued must be a date between 2000-01-01 and today",
+		"born must be a date in the past",
+	}, errorMessages(errs))
+
+	errs = ValidateCtx(WithLocale(context.Background(), "id"), bookingForm{Birthday: "x", Arrival: "2022-07-26 09:30", Issued: "2000-01-01", Born: day(-66, 0), StartDate: start})
+	assert.Equal(t, []string{
+		"birthday harus berupa tanggal dengan format DD/MM/YYYY, contoh 02/01/2006",
+		"born harus berupa tanggal lahir dengan usia maksimal 65 tahun",
+	}, errorMessages(errs))
+}
+
+func TestDateRulesEmptyField(t *testing.T) {
+	type period struct {
+		Start string `json:"start" validate:"before_field=End"`
+		End   string `json:"end" validate:"omitempty,after_field=Start"`
+	}
+	// an unset sibling is left to its own rules
+	assert.Nil(t, Validate(period{Start: "2020-01-01"}))
+	// start passes, end is not a date
+	errs := Validate(period{Start: "2020-01-01", End: "soon"})
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "end", errs[0].Field)
+	assert.Nil(t, Validate(period{Start: "2020-01-01", End: "2020-01-02"}))
+	assert.Equal(t, []string{
+		"start must be before End",
+		"end must be after Start",
+	}, errorMessages(Validate(period{Start: "2020-01-02", End: "2020-01-01"})))
+}
+
+func TestDateRulesLayouts(t *testing.T) {
+	type dmyForm struct {
+		Born     string   `json:"born" validate:"date=02/01/2006,past,age_min=17"`
+		Holidays []string `json:"holidays" validate:"dive,date=02/01/2006,daterange=2000-01-01~2100-01-01"`
+		Start    string   `json:"start" validate:"datetime=02/01/2006 15:04"`
+		End      string   `json:"end" validate:"date=02/01/2006,after_field=Start"`
+	}
+	// the rules read the layout of the date tag of the field
+	assert.Nil(t, Validate(dmyForm{Born: "17/08/1990", Holidays: []string{"25/12/2022"}, Start: "01/01/2022 09:00", End: "02/01/2022"}))
+	errs := Validate(dmyForm{Born: "17/08/2090", Holidays: []string{"25/12/1999"}, Start: "01/01/2022 09:00", End: "31/12/2021"})
+	assert.Equal(t, []string{
+		"born must be a date in the past",
+		"holidays[0] must be a date between 2000-01-01 and 2100-01-01",
+		"end must be after Start",
+	}, errorMessages(errs))
+
+	assert.Len(t, ValidateVar("17/08/1990", "past"), 1)
+	v := NewValidator(WithDateLayouts("02/01/2006"))
+	assert.Nil(t, v.ValidateVar("17/08/1990", "past"))
+	assert.Nil(t, v.ValidateVar("1990-08-17", "past"))
+	assert.Nil(t, v.ValidateVar("17/08/1990", "daterange=01/01/1990~today"))
+}
+
+func TestDateRulesLocation(t *testing.T) {
+	// the calendar days of UTC+14 and UTC-12 always differ
+	east, west := time.FixedZone("UTC+14", 14*60*60), time.FixedZone("UTC-12", -12*60*60)
+	today := time.Now().In(east).Format(DateLayout)
+
+	assert.Len(t, NewValidator(WithLocation(east)).ValidateVar(today, "future"), 1)
+	assert.Nil(t, NewValidator(WithLocation(west)).ValidateVar(today, "future"))
+	assert.Nil(t, NewValidator(WithLocation(east)).ValidateVar(today, "daterange=2000-01-01~today"))
+
+	// a date is read in the location, UTC+14 midnight is still the previous day in UTC
+	midnight, _ := time.ParseInLocation(DateLayout, "2022-07-26", east)
+	assert.Nil(t, ValidateVar(midnight, "daterange=2022-07-25~2022-07-25"))
+	assert.Len(t, NewValidator(WithLocation(east)).ValidateVar(midnight, "daterange=2022-07-25~2022-07-25"), 1)
+}
+
+func TestDateRulesVar(t *testing.T) {
+	assert.Nil(t, ValidateVar("2022-07-26", "date"))
+	assert.Nil(t, ValidateVar("2022-07-26T09:30:00+07:00", "datetime"))
+	assert.Len(t, ValidateVar("2022-07-26", "after_field=StartDate"), 1)
+	assert.Len(t, ValidateVar("2022-07-26", "daterange=2000-01-01"), 1)
+	assert.Len(t, ValidateVar(17, "age_min=17"), 1)
+
+	errs := ValidateVar("26-07-2022", "datetime")
+	assert.Equal(t, "value must be a date and time formatted as RFC 3339, e.g. 2006-01-02T15:04:05+07:00", errs[0].Message)
+}
+
+func TestDateSchema(t *testing.T) {
+	s := &jsonSchema{Type: "string"}
+	s.applyRules(timeType, "date=02/01/2006")
+	assert.Empty(t, s.Format)
+	s.applyRules(timeType, "date")
+	assert.Equal(t, "date", s.Format)
+}
@@ -0,0 +1,285 @@
+package valkyrie
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DotenvFiles are the dotenv files LoadConfig reads from each path by default,
+// variables of later files override earlier ones.
+var DotenvFiles = []string{".env", ".env.local"}
+
+var (
+	dotenvSetMtx sync.Mutex
+	// dotenvSet holds the variables LoadConfig set in the process environment
+	// with ConfigOpts.DotenvSetenv, they stay dotenv values on later loads.
+	dotenvSet = make(map[string]dotenvVar)
+)
+
+// dotenvVar is a variable set by LoadConfig and the dotenv file it comes from.
+type dotenvVar struct {
+	value string
+	file  string
+}
+
+// ParseDotenv parses dotenv content:
+//
+//	# comment
+//	export NAME=value            export prefixes are ignored
+//	PLAIN=value # comment        unquoted values are trimmed
+//	DOUBLE="line\nnext ${NAME}"  escapes and variables are expanded
+//	SINGLE='kept $AS is'         single quoted values are literal
+//	MULTI="first               quoted values may span lines
+//	second"
+//
+// Variables ($NAME, ${NAME} or ${NAME:-default}) resolve against the process
+// environment first, then the variables defined above them, \$ escapes a literal $.
+func ParseDotenv(r io.Reader) (map[string]string, error) {
+	vars := make(map[string]string)
+	_, err := parseDotenv(r, vars, os.LookupEnv)
+	return vars, err
+}
+
+// ReadDotenv parses the existing files in order, later files override earlier ones
+// and may refer to their variables. Missing files are skipped.
+func ReadDotenv(files ...string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for _, fp := range files {
+		if _, err := readDotenvFile(fp, vars); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return vars, nil
+}
+
+// LoadDotenv reads the files like ReadDotenv and sets their variables in the
+// process environment, variables already set in the environment are kept.
+func LoadDotenv(files ...string) error {
+	vars, err := ReadDotenv(files...)
+	if err != nil {
+		return err
+	}
+	return setenv(vars)
+}
+
+func setenv(vars map[string]string) error {
+	for k, v := range vars {
+		if _, ok := os.LookupEnv(k); ok {
+			continue
+		}
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processEnv looks name up in the process environment, leaving out the
+// variables LoadConfig set from dotenv files, unless they were changed since.
+func processEnv(name string) (string, bool) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", false
+	}
+	dotenvSetMtx.Lock()
+	defer dotenvSetMtx.Unlock()
+	if set, found := dotenvSet[name]; found && set.value == v {
+		return "", false
+	}
+	return v, true
+}
+
+// setDotenv sets vars, read from the dotenv files by origin, in the process environment
+// and unsets the variables it set before from the files that no longer define them.
+func setDotenv(vars, origin map[string]string, files []string) error {
+	dotenvSetMtx.Lock()
+	defer dotenvSetMtx.Unlock()
+	read := make(map[string]bool, len(files))
+	for _, fp := range files {
+		read[fp] = true
+	}
+	for k, set := range dotenvSet {
+		if _, ok := vars[k]; ok || !read[set.file] {
+			continue
+		}
+		if os.Getenv(k) == set.value {
+			if err := os.Unsetenv(k); err != nil {
+				return err
+			}
+		}
+		delete(dotenvSet, k)
+	}
+	for k, v := range vars {
+		if cur, ok := os.LookupEnv(k); ok && dotenvSet[k].value != cur {
+			// set outside of LoadConfig
+			delete(dotenvSet, k)
+			continue
+		}
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+		dotenvSet[k] = dotenvVar{value: v, file: origin[k]}
+	}
+	return nil
+}
+
+func readDotenvFile(fp string, vars map[string]string) ([]string, error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	names, err := parseDotenv(f, vars, processEnv)
+	if err != nil {
+		return nil, fmt.Errorf("dotenv file %s parsing error: %w", fp, err)
+	}
+	return names, nil
+}
+
+// parseDotenv adds the variables of r to vars and returns their names,
+// lookupEnv takes precedence over vars in expansions.
+func parseDotenv(r io.Reader, vars map[string]string, lookupEnv func(string) (string, bool)) ([]string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	lookup := func(name string) (string, bool) {
+		if v, ok := lookupEnv(name); ok {
+			return v, true
+		}
+		v, ok := vars[name]
+		return v, ok
+	}
+
+	src, line := strings.ReplaceAll(string(b), "\r\n", "\n"), 1
+	for len(src) > 0 {
+		var l string
+		if i := strings.IndexByte(src, '\n'); i >= 0 {
+			l, src = src[:i], src[i+1:]
+		} else {
+			l, src = src, ""
+		}
+		start := line
+		line++
+
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		l = strings.TrimSpace(strings.TrimPrefix(l, "export "))
+		eq := strings.IndexByte(l, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("line %d: expected NAME=value", start)
+		}
+		name, value := strings.TrimSpace(l[:eq]), strings.TrimLeft(l[eq+1:], " \t")
+		if strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("line %d: invalid name %q", start, name)
+		}
+		names = append(names, name)
+
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = value[:i]
+			}
+			vars[name] = expandDotenv(strings.TrimSpace(value), false, lookup)
+			continue
+		}
+
+		// a quoted value ends at the matching unescaped quote, possibly on a later line
+		quote := value[0]
+		value = value[1:]
+		end := closingQuote(value, quote)
+		for end < 0 && len(src) > 0 {
+			var next string
+			if i := strings.IndexByte(src, '\n'); i >= 0 {
+				next, src = src[:i], src[i+1:]
+			} else {
+				next, src = src, ""
+			}
+			line++
+			value += "\n" + next
+			end = closingQuote(value, quote)
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: unterminated quoted value", start)
+		}
+		if rest := strings.TrimSpace(value[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return nil, fmt.Errorf("line %d: unexpected %q after quoted value", start, rest)
+		}
+		value = value[:end]
+		if quote == '"' {
+			value = expandDotenv(value, true, lookup)
+		}
+		vars[name] = value
+	}
+	return names, nil
+}
+
+// closingQuote returns the index of the quote ending s, -1 when there is none.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			return i
+		}
+	}
+	return -1
+}
+
+var dotenvEscapes = map[byte]string{'n': "\n", 'r': "\r", 't': "\t", '"': `"`, '\\': `\`, '$': "$"}
+
+// expandDotenv expands the variables of s, and the backslash escapes of double quoted values.
+func expandDotenv(s string, escapes bool, lookup func(string) (string, bool)) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && (escapes || s[i+1] == '$'):
+			if e, ok := dotenvEscapes[s[i+1]]; ok {
+				out.WriteString(e)
+				i++
+				continue
+			}
+			out.WriteByte(c)
+		case c == '$' && i+1 < len(s) && s[i+1] == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				out.WriteString(s[i:])
+				return out.String()
+			}
+			name, def := s[i+2:i+end], ""
+			if j := strings.Index(name, ":-"); j >= 0 {
+				name, def = name[:j], name[j+2:]
+			}
+			if v, ok := lookup(name); ok && v != "" {
+				out.WriteString(v)
+			} else {
+				out.WriteString(def)
+			}
+			i += end
+		case c == '$' && i+1 < len(s) && isEnvNameByte(s[i+1]):
+			j := i + 1
+			for j < len(s) && isEnvNameByte(s[j]) {
+				j++
+			}
+			v, _ := lookup(s[i+1 : j])
+			out.WriteString(v)
+			i = j - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+func isEnvNameByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,171 @@
+package valkyrie
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDotenv(t *testing.T) {
+	t.Setenv("DOTENV_HOME", "/home/luffy")
+	vars, err := ParseDotenv(strings.NewReader(`
+# comment
+export NAME=laugh-tale
+PLAIN =  value with spaces   # comment
+EMPTY=
+DOUBLE="line\nnext \"${NAME}\" \$NAME"
+SINGLE='kept $NAME \n'
+MULTI="first
+second"
+KEY='-----BEGIN-----
+abc
+-----END-----' # pem
+PATH_REF=$DOTENV_HOME/bin:${MISSING:-/usr/bin}
+ESCAPED=\$NAME
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"NAME":     "laugh-tale",
+		"PLAIN":    "value with spaces",
+		"EMPTY":    "",
+		"DOUBLE":   "line\nnext \"laugh-tale\" $NAME",
+		"SINGLE":   `kept $NAME \n`,
+		"MULTI":    "first\nsecond",
+		"KEY":      "-----BEGIN-----\nabc\n-----END-----",
+		"PATH_REF": "/home/luffy/bin:/usr/bin",
+		"ESCAPED":  "$NAME",
+	}, vars)
+
+	for _, content := range []string{"NOVALUE", "A B=c", `QUOTE="open`, `QUOTE="a" b`} {
+		_, err = ParseDotenv(strings.NewReader(content))
+		assert.Error(t, err, content)
+	}
+}
+
+func TestReadDotenv(t *testing.T) {
+	dir := t.TempDir()
+	env := writeConfig(t, dir, ".env", "HOST=localhost\nPORT=3000\n")
+	local := writeConfig(t, dir, ".env.local", "PORT=4000\nURL=http://${HOST}:${PORT}\n")
+
+	vars, err := ReadDotenv(env, local, dir+"/missing")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"HOST": "localhost", "PORT": "4000", "URL": "http://localhost:4000"}, vars)
+}
+
+func TestLoadDotenv(t *testing.T) {
+	dir := t.TempDir()
+	fp := writeConfig(t, dir, ".env", "DOTENV_KEPT=file\nDOTENV_SET=file\n")
+	t.Setenv("DOTENV_KEPT", "process")
+	t.Setenv("DOTENV_SET", "")
+	assert.NoError(t, os.Unsetenv("DOTENV_SET"))
+
+	assert.NoError(t, LoadDotenv(fp))
+	assert.Equal(t, "process", os.Getenv("DOTENV_KEPT"))
+	assert.Equal(t, "file", os.Getenv("DOTENV_SET"))
+}
+
+type dotenvConfig struct {
+	App struct {
+		Host string   `yaml:"host" env:"DOTENV_APP_HOST"`
+		Port int      `yaml:"port" env:"DOTENV_APP_PORT"`
+		Name string   `yaml:"name" env:"DOTENV_APP_NAME"`
+		Zone Location `yaml:"zone" env:"DOTENV_APP_ZONE"`
+		DSN  string   `yaml:"dsn"`
+	} `yaml:"App"`
+}
+
+func TestConfigDotenvPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	fp := writeConfig(t, dir, "app.yaml", "App:\n  host: file\n  port: 1\n  name: file\n  zone: UTC\n  dsn: postgres://${DOTENV_APP_HOST}\n")
+	env := writeConfig(t, dir, ".env", "DOTENV_APP_HOST=dotenv\nDOTENV_APP_PORT=2\nDOTENV_APP_NAME=dotenv\nDOTENV_APP_ZONE=Asia/Jakarta\n")
+	local := writeConfig(t, dir, ".env.local", "DOTENV_APP_PORT=3\nDOTENV_APP_NAME=local\n")
+	t.Setenv("DOTENV_APP_NAME", "process")
+
+	cfg := &dotenvConfig{}
+	report, err := LoadConfig(ConfigOpts{Config: cfg, Filenames: []string{"app.yaml"}, Paths: []string{dir}})
+	assert.NoError(t, err)
+	assert.Equal(t, "dotenv", cfg.App.Host)
+	assert.Equal(t, 3, cfg.App.Port)
+	assert.Equal(t, "process", cfg.App.Name)
+	assert.Equal(t, Location("Asia/Jakarta"), cfg.App.Zone)
+	assert.Equal(t, "postgres://dotenv", cfg.App.DSN)
+
+	assert.Equal(t, []string{env, local}, report.Dotenv)
+	assert.Equal(t, "env DOTENV_APP_HOST ("+env+")", report.Origins["App.host"])
+	assert.Equal(t, "env DOTENV_APP_PORT ("+local+")", report.Origins["App.port"])
+	assert.Equal(t, "env DOTENV_APP_NAME", report.Origins["App.name"])
+	assert.Equal(t, fp, report.Origins["App.dsn"])
+
+	// the process environment is left alone by default
+	_, ok := os.LookupEnv("DOTENV_APP_HOST")
+	assert.False(t, ok)
+}
+
+func TestConfigDotenvSetenv(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "app.yaml", "App:\n  host: file\n")
+	writeConfig(t, dir, ".env", "DOTENV_APP_HOST=dotenv\n")
+	t.Setenv("DOTENV_APP_HOST", "")
+	assert.NoError(t, os.Unsetenv("DOTENV_APP_HOST"))
+
+	cfg := &dotenvConfig{}
+	report, err := LoadConfig(ConfigOpts{
+		Config:       cfg,
+		Filenames:    []string{"app.yaml"},
+		Paths:        []string{dir},
+		DotenvSetenv: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "dotenv", cfg.App.Host)
+	assert.Equal(t, "dotenv", os.Getenv("DOTENV_APP_HOST"))
+	assert.Contains(t, report.Origins["App.host"], ".env)")
+}
+
+func TestConfigDotenvSetenvReload(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "app.yaml", "App:\n  host: file\n")
+	env := writeConfig(t, dir, ".env", "DOTENV_APP_HOST=info\n")
+	t.Setenv("DOTENV_APP_HOST", "")
+	assert.NoError(t, os.Unsetenv("DOTENV_APP_HOST"))
+
+	opts := ConfigOpts{
+		Config:       &dotenvConfig{},
+		Filenames:    []string{"app.yaml"},
+		Paths:        []string{dir},
+		DotenvSetenv: true,
+	}
+	origin := func() string {
+		opts.Config = &dotenvConfig{}
+		report, err := LoadConfig(opts)
+		assert.NoError(t, err)
+		return report.Origins["App.host"]
+	}
+	w, err := WatchConfig(opts, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, "info", w.Current().(*dotenvConfig).App.Host)
+
+	// the variable set from .env follows the file
+	writeConfig(t, dir, ".env", "DOTENV_APP_HOST=debug\n")
+	assert.NoError(t, w.Reload())
+	assert.Equal(t, "debug", w.Current().(*dotenvConfig).App.Host)
+	assert.Equal(t, "debug", os.Getenv("DOTENV_APP_HOST"))
+	assert.Equal(t, "env DOTENV_APP_HOST ("+env+")", origin())
+
+	// and is unset with it
+	writeConfig(t, dir, ".env", "\n")
+	assert.NoError(t, w.Reload())
+	assert.Equal(t, "file", w.Current().(*dotenvConfig).App.Host)
+	_, ok := os.LookupEnv("DOTENV_APP_HOST")
+	assert.False(t, ok)
+
+	// a value set in the environment wins again
+	writeConfig(t, dir, ".env", "DOTENV_APP_HOST=debug\n")
+	assert.NoError(t, w.Reload())
+	assert.NoError(t, os.Setenv("DOTENV_APP_HOST", "warn"))
+	assert.NoError(t, w.Reload())
+	assert.Equal(t, "warn", w.Current().(*dotenvConfig).App.Host)
+	assert.Equal(t, "env DOTENV_APP_HOST", origin())
+}
@@ -0,0 +1,197 @@
+package valkyrie
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Masked replaces the value of secret config keys in DumpConfig.
+const Masked = "******"
+
+// secretKeys are key names masked by DumpConfig even without a secret tag.
+var secretKeys = []string{"secret", "password", "passwd", "token", "dsn", "api_key", "apikey", "private_key", "credential"}
+
+var (
+	yamlMarshalerType = reflect.TypeOf((*yaml.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// DumpOpts configures DumpConfig.
+type DumpOpts struct {
+	// Format is "yaml" (default) or "json".
+	Format string
+	// Report annotates every key with the origin of its value,
+	// keys missing from the report are shown as "default".
+	Report *ConfigReport
+}
+
+// DumpConfig renders the effective configuration cfg for debugging.
+// Fields tagged secret:"true", along with the structs, maps and slices below them,
+// and keys with well-known secret names (secret_key, dsn_main, password, ...),
+// map keys included, are replaced with Masked.
+//
+// YAML output carries the origin of each value as a line comment,
+// JSON output is {"config": {...}, "sources": {"App.port": "app.yaml", ...}}.
+func DumpConfig(cfg interface{}, opts DumpOpts) ([]byte, error) {
+	origins := map[string]string{}
+	if opts.Report != nil {
+		origins = opts.Report.Origins
+	}
+	d := &dumper{origins: origins, sources: make(map[string]string)}
+	node, err := d.node(reflect.ValueOf(cfg), "", false, true)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(opts.Format) {
+	case "", "yaml", "yml":
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err = enc.Encode(node); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), enc.Close()
+	case "json":
+		var tree interface{}
+		if err = node.Decode(&tree); err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(map[string]interface{}{
+			"config":  tree,
+			"sources": d.sources,
+		}, "", "  ")
+	default:
+		return nil, fmt.Errorf("dump format '%s' is not supported", opts.Format)
+	}
+}
+
+type dumper struct {
+	origins map[string]string
+	sources map[string]string
+}
+
+// node builds the yaml node of v, key is its key path. secret masks v and everything below
+// it, and annotate records the origin of v, the values of maps and slices share the origin
+// of their collection.
+func (d *dumper) node(v reflect.Value, key string, secret, annotate bool) (*yaml.Node, error) {
+	for v.Kind() == reflect.Interface && !v.IsNil() || v.Kind() == reflect.Ptr && !v.IsNil() && isConfigStruct(v.Type()) {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct && isConfigStruct(v.Type()) {
+		n := &yaml.Node{Kind: yaml.MappingNode}
+		for i := 0; i < v.NumField(); i++ {
+			fld := v.Type().Field(i)
+			name := configKey(fld)
+			if fld.PkgPath != "" || name == "-" {
+				continue
+			}
+			child, err := d.node(v.Field(i), joinKey(key, name), secret || fld.Tag.Get("secret") == "true", annotate)
+			if err != nil {
+				return nil, err
+			}
+			keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: name}
+			if child.Kind != yaml.ScalarNode {
+				// yaml renders the comment of a collection after its key
+				keyNode.LineComment, child.LineComment = child.LineComment, ""
+			}
+			n.Content = append(n.Content, keyNode, child)
+		}
+		return n, nil
+	}
+
+	secret = secret || isSecretKey(key)
+	n := &yaml.Node{}
+	var err error
+	switch {
+	case isCollection(v):
+		n, err = d.collection(v, key, secret)
+	case secret && v.IsValid() && !v.IsZero():
+		err = n.Encode(Masked)
+	default:
+		var value interface{}
+		if v.IsValid() {
+			value = v.Interface()
+		}
+		err = n.Encode(value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", key, err)
+	}
+	if !annotate {
+		return n, nil
+	}
+	origin, ok := d.origins[key]
+	if !ok {
+		origin = "default"
+	}
+	d.sources[key] = origin
+	n.LineComment = origin
+	return n, nil
+}
+
+// collection builds the yaml node of a map or a slice element by element,
+// so secret values and the values of secret keys such as labels.password are masked.
+func (d *dumper) collection(v reflect.Value, key string, secret bool) (*yaml.Node, error) {
+	if v.Kind() != reflect.Map {
+		n := &yaml.Node{Kind: yaml.SequenceNode}
+		for i := 0; i < v.Len(); i++ {
+			child, err := d.node(v.Index(i), fmt.Sprintf("%s[%d]", key, i), secret, false)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, child)
+		}
+		return n, nil
+	}
+
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	n := &yaml.Node{Kind: yaml.MappingNode}
+	for _, k := range keys {
+		name := &yaml.Node{}
+		if err := name.Encode(k.Interface()); err != nil {
+			return nil, fmt.Errorf("config %s: %w", key, err)
+		}
+		child, err := d.node(v.MapIndex(k), joinKey(key, fmt.Sprint(k.Interface())), secret, false)
+		if err != nil {
+			return nil, err
+		}
+		n.Content = append(n.Content, name, child)
+	}
+	return n, nil
+}
+
+// isCollection reports whether v is a non-empty map, slice or array dumped element by element,
+// bytes and types marshalling themselves are dumped as a whole.
+func isCollection(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+	default:
+		return false
+	}
+	t := v.Type()
+	if t.Elem().Kind() == reflect.Uint8 || t.Implements(yamlMarshalerType) || t.Implements(textMarshalerType) {
+		return false
+	}
+	return v.Len() > 0
+}
+
+func isSecretKey(key string) bool {
+	name := strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	for _, s := range secretKeys {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package valkyrie
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type dumpConfig struct {
+	App struct {
+		Name      string `yaml:"name"`
+		Port      int    `yaml:"port" env:"APP_PORT"`
+		SecretKey string `yaml:"secret_key"`
+		Signing   string `yaml:"signing" secret:"true"`
+		Empty     string `yaml:"empty" secret:"true"`
+	} `yaml:"App"`
+	DB struct {
+		DsnMain string `yaml:"dsn_main" env:"DSN_MAIN"`
+	}
+}
+
+func TestDumpConfig(t *testing.T) {
+	dir := t.TempDir()
+	fp := writeConfig(t, dir, "app.yaml", "App:\n  name: laugh-tale\n  port: 8778\n  secret_key: sekret\n  signing: hmac\n")
+	t.Setenv("DSN_MAIN", "host=localhost password=root123")
+
+	var cfg dumpConfig
+	report, err := LoadConfig(ConfigOpts{
+		Config:    &cfg,
+		Filenames: []string{"app.yaml"},
+		Paths:     []string{dir},
+	})
+	assert.NoError(t, err)
+
+	out, err := DumpConfig(&cfg, DumpOpts{Report: report})
+	assert.NoError(t, err)
+	assert.Equal(t, `App:
+  name: laugh-tale # `+fp+`
+  port: 8778 # `+fp+`
+  secret_key: '******' # `+fp+`
+  signing: '******' # `+fp+`
+  empty: "" # default
+db:
+  dsn_main: '******' # env DSN_MAIN
+`, string(out))
+
+	out, err = DumpConfig(&cfg, DumpOpts{Report: report, Format: "json"})
+	assert.NoError(t, err)
+	var dump struct {
+		Config  map[string]map[string]interface{} `json:"config"`
+		Sources map[string]string                 `json:"sources"`
+	}
+	assert.NoError(t, json.Unmarshal(out, &dump))
+	assert.Equal(t, Masked, dump.Config["db"]["dsn_main"])
+	assert.Equal(t, float64(8778), dump.Config["App"]["port"])
+	assert.Equal(t, "env DSN_MAIN", dump.Sources["db.dsn_main"])
+	assert.NotContains(t, string(out), "sekret")
+
+	_, err = DumpConfig(&cfg, DumpOpts{Format: "xml"})
+	assert.Error(t, err)
+}
+
+func TestDumpConfigNested(t *testing.T) {
+	type credentials struct {
+		User string `yaml:"user"`
+		Key  string `yaml:"key"`
+	}
+	var cfg struct {
+		Auth    credentials            `yaml:"auth" secret:"true"`
+		Tokens  map[string]string      `yaml:"tokens"`
+		Keys    map[string]string      `yaml:"keys" secret:"true"`
+		Labels  map[string]interface{} `yaml:"labels"`
+		Servers []credentials          `yaml:"servers"`
+	}
+	cfg.Auth = credentials{User: "admin", Key: "k3y"}
+	cfg.Tokens = map[string]string{"github": "ghp_x"}
+	cfg.Keys = map[string]string{"signing": "hmac"}
+	cfg.Labels = map[string]interface{}{"team": "core", "password": "root123", "db": map[string]interface{}{"api_key": "abc"}}
+	cfg.Servers = []credentials{{User: "deploy"}}
+
+	out, err := DumpConfig(&cfg, DumpOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, `auth:
+  user: '******' # default
+  key: '******' # default
+tokens: # default
+  github: '******'
+keys: # default
+  signing: '******'
+labels: # default
+  db:
+    api_key: '******'
+  password: '******'
+  team: core
+servers: # default
+  - user: deploy
+    key: ""
+`, string(out))
+	for _, secret := range []string{"admin", "k3y", "ghp_x", "hmac", "root123", "abc"} {
+		assert.NotContains(t, string(out), secret)
+	}
+}
@@ -0,0 +1,129 @@
+package valkyrie
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	enumsMtx sync.RWMutex
+	// enums holds the named enum sets by name, see RegisterEnum.
+	enums = map[string][]string{}
+)
+
+// RegisterEnum names a set of allowed values, e.g. the constants of a type:
+//
+//	RegisterEnum("OrderStatus", StatusPending, StatusPaid, StatusShipped)
+//
+// so that `validate:"enum=@OrderStatus"` accepts them without repeating the values.
+func RegisterEnum(name string, values ...interface{}) {
+	set := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := enumString(reflect.ValueOf(v)); ok {
+			set = append(set, s)
+		}
+	}
+	enumsMtx.Lock()
+	enums[name] = set
+	enumsMtx.Unlock()
+}
+
+// WithEnumCaseSensitive makes the enum tag compare strings case-sensitively, like enum_strict.
+func WithEnumCaseSensitive() ValidatorOption {
+	return func(v *Validator) {
+		_ = v.validate.RegisterValidation("enum", EnumStrictValidation)
+	}
+}
+
+// ParseTags validates the enum tag, e.g. enum=ovo gopay 'bank transfer' or enum=@OrderStatus,
+// strings are compared case-insensitively. Pointers are dereferenced and every
+// element of a slice or an array must be allowed.
+func ParseTags(fl validator.FieldLevel) bool {
+	return matchEnum(fl.Field(), enumValues(fl.Param()), false)
+}
+
+// EnumStrictValidation validates the enum_strict tag, it works like enum
+// but compares strings case-sensitively.
+func EnumStrictValidation(fl validator.FieldLevel) bool {
+	return matchEnum(fl.Field(), enumValues(fl.Param()), true)
+}
+
+// enumValues returns the allowed values of an enum parameter, either the set registered
+// as @name or a list of values. Only @ names a set, so registering a set never changes
+// the meaning of a list such as enum=paid, an unknown set allows nothing and a value
+// starting with @ is quoted, enum='@home'.
+func enumValues(param string) []string {
+	if name := strings.TrimSpace(param); strings.HasPrefix(name, "@") {
+		enumsMtx.RLock()
+		defer enumsMtx.RUnlock()
+		return enums[name[1:]]
+	}
+	return splitEnumParams(param)
+}
+
+func matchEnum(v reflect.Value, allowed []string, caseSensitive bool) bool {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !matchEnum(v.Index(i), allowed, caseSensitive) {
+				return false
+			}
+		}
+		return true
+	}
+
+	s, ok := enumString(v)
+	if !ok {
+		return false
+	}
+	for _, a := range allowed {
+		switch v.Kind() {
+		case reflect.String:
+			if s == a || !caseSensitive && strings.EqualFold(s, a) {
+				return true
+			}
+		case reflect.Float32, reflect.Float64:
+			// 1.5 matches 1.50
+			if f, err := strconv.ParseFloat(a, 64); err == nil && f == v.Float() {
+				return true
+			}
+		case reflect.Bool:
+			if b, err := strconv.ParseBool(a); err == nil && b == v.Bool() {
+				return true
+			}
+		default:
+			if s == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// enumString formats a scalar by its kind, ignoring any String method,
+// so constants of a type with a String method register their underlying value.
+func enumString(v reflect.Value) (string, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	}
+	return "", false
+}
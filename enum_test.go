@@ -0,0 +1,122 @@
+package valkyrie
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	orderStatus string
+	priority    int
+)
+
+const (
+	statusPending orderStatus = "pending"
+	statusPaid    orderStatus = "paid"
+
+	priorityLow  priority = 1
+	priorityHigh priority = 3
+)
+
+func (p priority) String() string {
+	return map[priority]string{priorityLow: "low", priorityHigh: "high"}[p]
+}
+
+func init() {
+	RegisterEnum("OrderStatus", statusPending, statusPaid)
+	RegisterEnum("Priority", priorityLow, priorityHigh)
+}
+
+type enumForm struct {
+	Payment  string        `json:"payment" validate:"enum=ovo gopay 'bank transfer'"`
+	Code     string        `json:"code" validate:"omitempty,enum_strict=ABC xyz"`
+	Status   orderStatus   `json:"status" validate:"enum=@OrderStatus"`
+	Statuses []orderStatus `json:"statuses" validate:"enum=@OrderStatus"`
+	Priority priority      `json:"priority" validate:"enum=@Priority"`
+	Rate     float64       `json:"rate" validate:"enum=0.5 1.50 2"`
+	Active   *bool         `json:"active" validate:"omitempty,enum=true"`
+	Sizes    [2]uint       `json:"sizes" validate:"enum=0 38 40"`
+}
+
+func TestEnum(t *testing.T) {
+	active := true
+	valid := enumForm{
+		Payment:  "Bank Transfer",
+		Code:     "ABC",
+		Status:   statusPaid,
+		Statuses: []orderStatus{"PENDING", statusPaid},
+		Priority: priorityHigh,
+		Rate:     1.5,
+		Active:   &active,
+		Sizes:    [2]uint{38, 40},
+	}
+	assert.Nil(t, Validate(valid))
+
+	inactive := false
+	invalid := enumForm{
+		Payment:  "cash",
+		Code:     "abc",
+		Status:   "lost",
+		Statuses: []orderStatus{statusPaid, "lost"},
+		Priority: 2,
+		Rate:     1.25,
+		Active:   &inactive,
+		Sizes:    [2]uint{38, 42},
+	}
+	errs := Validate(invalid)
+	assert.Equal(t, []string{
+		"payment must be one of: ovo, gopay, bank transfer",
+		"code must be one of: ABC, xyz",
+		"status must be one of: pending, paid",
+		"statuses must be one of: pending, paid",
+		"priority must be one of: 1, 3",
+		"rate must be one of: 0.5, 1.50, 2",
+		"active must be one of: true",
+		"sizes must be one of: 0, 38, 40",
+	}, errorMessages(errs))
+
+	strict := NewValidator(WithEnumCaseSensitive())
+	errs = strict.Validate(valid)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "payment", errs[0].Field)
+	assert.Equal(t, "statuses", errs[1].Field)
+}
+
+func TestEnumVar(t *testing.T) {
+	assert.Nil(t, ValidateVar("paid", "enum=@OrderStatus"))
+	assert.Len(t, ValidateVar(map[string]string{}, "enum=@OrderStatus"), 1)
+
+	// a registered set does not change a list of values
+	RegisterEnum("paid", "refunded")
+	defer func() {
+		enumsMtx.Lock()
+		delete(enums, "paid")
+		enumsMtx.Unlock()
+	}()
+	assert.Nil(t, ValidateVar("paid", "enum=paid"))
+	assert.Len(t, ValidateVar("refunded", "enum=paid"), 1)
+	assert.Nil(t, ValidateVar("refunded", "enum=@paid"))
+	assert.Len(t, ValidateVar("paid", "enum=@Unknown"), 1)
+	assert.Nil(t, ValidateVar("@home", "enum='@home' office"))
+}
+
+func TestEnumSchema(t *testing.T) {
+	b, err := ConfigSchema(&struct {
+		Status   orderStatus   `yaml:"status" validate:"enum=@OrderStatus"`
+		Statuses []orderStatus `yaml:"statuses" validate:"enum=@OrderStatus"`
+	}{})
+	assert.NoError(t, err)
+	var schema struct {
+		Properties map[string]struct {
+			Enum  []string `json:"enum"`
+			Items struct {
+				Enum []string `json:"enum"`
+			} `json:"items"`
+		} `json:"properties"`
+	}
+	assert.NoError(t, json.Unmarshal(b, &schema))
+	assert.Equal(t, []string{"pending", "paid"}, schema.Properties["status"].Enum)
+	assert.Equal(t, []string{"pending", "paid"}, schema.Properties["statuses"].Items.Enum)
+}
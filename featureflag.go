@@ -0,0 +1,198 @@
+package valkyrie
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// FeatureFlagFieldName is the log field holding the evaluated flags, see FeatureFlagHook.
+const FeatureFlagFieldName = "feature_flags"
+
+type (
+	// FeatureFlag is the declaration of a flag in the config file:
+	//
+	//	flags:
+	//	  new_checkout:
+	//	    enabled: true
+	//	    percentage: 25        # sticky rollout on user_id, or tenant without user
+	//	    rules:                # every rule must match
+	//	      - attribute: tenant
+	//	        values: [acme, globex]
+	//	      - attribute: env
+	//	        values: [staging, production]
+	FeatureFlag struct {
+		Enabled    bool              `yaml:"enabled" json:"enabled"`
+		Percentage *float64          `yaml:"percentage" json:"percentage,omitempty"`
+		Rules      []FeatureFlagRule `yaml:"rules" json:"rules,omitempty"`
+	}
+
+	// FeatureFlagRule matches when the attribute has one of the values.
+	FeatureFlagRule struct {
+		Attribute string   `yaml:"attribute" json:"attribute"`
+		Values    []string `yaml:"values" json:"values"`
+	}
+
+	// FlagContext holds the attributes flags are evaluated against.
+	FlagContext struct {
+		UserID     string
+		Tenant     string
+		Env        string
+		Attributes map[string]string
+	}
+
+	// FeatureFlags evaluates flags declared in the config,
+	// Update swaps the declarations atomically on hot reload.
+	FeatureFlags struct {
+		env   string
+		flags atomic.Value
+	}
+
+	flagState struct {
+		FlagContext
+		mtx       sync.Mutex
+		evaluated map[string]bool
+	}
+
+	flagStateKey struct{}
+)
+
+// NewFeatureFlags creates an evaluator of flags, env is used
+// when the FlagContext does not carry one, e.g. cfg.App.Env.
+func NewFeatureFlags(flags map[string]FeatureFlag, env string) *FeatureFlags {
+	f := &FeatureFlags{env: env}
+	f.Update(flags)
+	return f
+}
+
+// Update replaces the flag declarations.
+func (f *FeatureFlags) Update(flags map[string]FeatureFlag) {
+	if flags == nil {
+		flags = map[string]FeatureFlag{}
+	}
+	f.flags.Store(flags)
+}
+
+// Watch keeps the declarations up to date with the config reloaded by w,
+// get returns the flags of a config loaded by the watcher.
+func (f *FeatureFlags) Watch(w *ConfigWatcher, get func(cfg interface{}) map[string]FeatureFlag) {
+	w.Subscribe(func(_, cfg interface{}) {
+		f.Update(get(cfg))
+	})
+}
+
+// WithFlagContext returns a context carrying the attributes of fc
+// and recording the flags evaluated with it.
+func WithFlagContext(ctx context.Context, fc FlagContext) context.Context {
+	return context.WithValue(ctx, flagStateKey{}, &flagState{FlagContext: fc, evaluated: map[string]bool{}})
+}
+
+// Enabled evaluates the flag name for the FlagContext of ctx, unknown flags are disabled.
+// The result is recorded as the span attribute feature_flag.<name> and in FeatureFlagHook.
+func (f *FeatureFlags) Enabled(ctx context.Context, name string) bool {
+	state, _ := ctx.Value(flagStateKey{}).(*flagState)
+	if state == nil {
+		state = &flagState{}
+	}
+	// the state is shared by every evaluator using ctx, the env of f only applies to this call
+	fc := state.FlagContext
+	if fc.Env == "" {
+		fc.Env = f.env
+	}
+
+	flag, ok := f.flags.Load().(map[string]FeatureFlag)[name]
+	enabled := ok && flag.evaluate(name, fc)
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("feature_flag."+name, enabled))
+	state.mtx.Lock()
+	if state.evaluated != nil {
+		state.evaluated[name] = enabled
+	}
+	state.mtx.Unlock()
+	return enabled
+}
+
+func (flag FeatureFlag) evaluate(name string, fc FlagContext) bool {
+	if !flag.Enabled {
+		return false
+	}
+	for _, rule := range flag.Rules {
+		v, ok := fc.attribute(rule.Attribute)
+		if !ok || !contains(rule.Values, v) {
+			return false
+		}
+	}
+	if flag.Percentage == nil {
+		return true
+	}
+	id := fc.UserID
+	if id == "" {
+		id = fc.Tenant
+	}
+	if id == "" {
+		return false
+	}
+	return flagBucket(name, id) < *flag.Percentage
+}
+
+func (fc FlagContext) attribute(name string) (string, bool) {
+	switch name {
+	case "user_id":
+		return fc.UserID, fc.UserID != ""
+	case "tenant":
+		return fc.Tenant, fc.Tenant != ""
+	case "env":
+		return fc.Env, fc.Env != ""
+	}
+	v, ok := fc.Attributes[name]
+	return v, ok
+}
+
+// flagBucket places id in [0, 100) for the flag name, the same id always
+// lands in the same bucket so a rollout stays sticky while it grows.
+func flagBucket(name, id string) float64 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name + ":" + id))
+	return float64(h.Sum32()%10000) / 100
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// FeatureFlagHook returns a zerolog.Hook adding the flags evaluated
+// with the FlagContext of ctx to log events.
+func FeatureFlagHook(ctx context.Context) zerolog.Hook {
+	return featureFlagHook{ctx}
+}
+
+type featureFlagHook struct {
+	ctx context.Context
+}
+
+func (h featureFlagHook) Run(e *zerolog.Event, level zerolog.Level, message string) {
+	state, _ := h.ctx.Value(flagStateKey{}).(*flagState)
+	if state == nil {
+		return
+	}
+	state.mtx.Lock()
+	defer state.mtx.Unlock()
+	if len(state.evaluated) == 0 {
+		return
+	}
+	d := zerolog.Dict()
+	for name, enabled := range state.evaluated {
+		d.Bool(name, enabled)
+	}
+	e.Dict(FeatureFlagFieldName, d)
+}
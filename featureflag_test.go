@@ -0,0 +1,172 @@
+package valkyrie
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type featureFlagConfig struct {
+	App struct {
+		Env string `yaml:"env"`
+	} `yaml:"App"`
+	Flags map[string]FeatureFlag `yaml:"flags"`
+}
+
+const flagYAML = `
+App:
+  env: staging
+flags:
+  dark_mode:
+    enabled: true
+  off:
+    enabled: false
+  checkout:
+    enabled: true
+    percentage: 30
+  tenants:
+    enabled: true
+    rules:
+      - attribute: tenant
+        values: [acme, globex]
+      - attribute: env
+        values: [staging]
+  beta:
+    enabled: true
+    rules:
+      - attribute: plan
+        values: [pro]
+`
+
+func loadFlags(t *testing.T) *FeatureFlags {
+	t.Helper()
+	dir := t.TempDir()
+	writeConfig(t, dir, "app.yaml", flagYAML)
+	cfg := &featureFlagConfig{}
+	_, err := LoadConfig(ConfigOpts{Config: cfg, Filenames: []string{"app.yaml"}, Paths: []string{dir}})
+	assert.NoError(t, err)
+	return NewFeatureFlags(cfg.Flags, cfg.App.Env)
+}
+
+func TestFeatureFlagsEnabled(t *testing.T) {
+	flags := loadFlags(t)
+	ctx := context.Background()
+
+	assert.True(t, flags.Enabled(ctx, "dark_mode"))
+	assert.False(t, flags.Enabled(ctx, "off"))
+	assert.False(t, flags.Enabled(ctx, "unknown"))
+	// rollout needs an id
+	assert.False(t, flags.Enabled(ctx, "checkout"))
+
+	acme := WithFlagContext(ctx, FlagContext{Tenant: "acme"})
+	assert.True(t, flags.Enabled(acme, "tenants"))
+	assert.False(t, flags.Enabled(WithFlagContext(ctx, FlagContext{Tenant: "initech"}), "tenants"))
+	assert.False(t, flags.Enabled(WithFlagContext(ctx, FlagContext{Tenant: "acme", Env: "production"}), "tenants"))
+
+	assert.True(t, flags.Enabled(WithFlagContext(ctx, FlagContext{Attributes: map[string]string{"plan": "pro"}}), "beta"))
+	assert.False(t, flags.Enabled(WithFlagContext(ctx, FlagContext{Attributes: map[string]string{"plan": "free"}}), "beta"))
+}
+
+func TestFeatureFlagsSharedContext(t *testing.T) {
+	rules := map[string]FeatureFlag{"staging_only": {Enabled: true, Rules: []FeatureFlagRule{{Attribute: "env", Values: []string{"staging"}}}}}
+	production, staging := NewFeatureFlags(rules, "production"), NewFeatureFlags(rules, "staging")
+	ctx := WithFlagContext(context.Background(), FlagContext{Tenant: "acme"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.False(t, production.Enabled(ctx, "staging_only"))
+		}()
+		go func() {
+			defer wg.Done()
+			assert.True(t, staging.Enabled(ctx, "staging_only"))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFeatureFlagsRollout(t *testing.T) {
+	flags := loadFlags(t)
+	enabled := 0
+	for i := 0; i < 1000; i++ {
+		ctx := WithFlagContext(context.Background(), FlagContext{UserID: fmt.Sprintf("user-%d", i)})
+		v := flags.Enabled(ctx, "checkout")
+		// sticky
+		assert.Equal(t, v, flags.Enabled(ctx, "checkout"))
+		if v {
+			enabled++
+		}
+	}
+	assert.InDelta(t, 300, enabled, 60)
+
+	// raising the percentage keeps enabled users enabled
+	pct := 60.0
+	wider := NewFeatureFlags(map[string]FeatureFlag{"checkout": {Enabled: true, Percentage: &pct}}, "")
+	for i := 0; i < 1000; i++ {
+		ctx := WithFlagContext(context.Background(), FlagContext{UserID: fmt.Sprintf("user-%d", i)})
+		if flags.Enabled(ctx, "checkout") {
+			assert.True(t, wider.Enabled(ctx, "checkout"))
+		}
+	}
+}
+
+func TestFeatureFlagsRecord(t *testing.T) {
+	flags := loadFlags(t)
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "flags")
+	ctx = WithFlagContext(ctx, FlagContext{Tenant: "acme"})
+
+	flags.Enabled(ctx, "dark_mode")
+	flags.Enabled(ctx, "off")
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Hook(FeatureFlagHook(ctx))
+	logger.Info().Msg("checkout")
+	assert.Contains(t, buf.String(), `"feature_flags":{`)
+	assert.Contains(t, buf.String(), `"dark_mode":true`)
+	assert.Contains(t, buf.String(), `"off":false`)
+
+	span.End()
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.Contains(t, spans[0].Attributes(), attribute.Bool("feature_flag.dark_mode", true))
+	assert.Contains(t, spans[0].Attributes(), attribute.Bool("feature_flag.off", false))
+}
+
+func TestFeatureFlagsWatch(t *testing.T) {
+	dir := t.TempDir()
+	fp := writeConfig(t, dir, "app.yaml", "flags:\n  dark_mode:\n    enabled: false\n")
+	w, err := WatchConfig(ConfigOpts{
+		Config:    &featureFlagConfig{},
+		Filenames: []string{"app.yaml"},
+		Paths:     []string{dir},
+	}, 10*time.Millisecond)
+	assert.NoError(t, err)
+
+	flags := NewFeatureFlags(w.Current().(*featureFlagConfig).Flags, "")
+	flags.Watch(w, func(cfg interface{}) map[string]FeatureFlag {
+		return cfg.(*featureFlagConfig).Flags
+	})
+	assert.False(t, flags.Enabled(context.Background(), "dark_mode"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	touchConfig(t, fp, "flags:\n  dark_mode:\n    enabled: true\n", time.Now().Add(time.Minute))
+	assert.Eventually(t, func() bool {
+		return flags.Enabled(context.Background(), "dark_mode")
+	}, time.Second, 10*time.Millisecond)
+}
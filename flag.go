@@ -0,0 +1,116 @@
+package valkyrie
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+type (
+	// ConfigFlags binds a command-line flag to every key of a config struct,
+	// named after the lowercased key path, e.g. --app.port=9000.
+	// Pass it as ConfigOpts.Flags so set flags override every other value.
+	ConfigFlags struct {
+		metas []configMeta
+		flags map[string]*configFlag
+	}
+
+	configFlag struct {
+		name  string
+		value string
+		set   bool
+		typ   reflect.Type
+	}
+)
+
+// BindConfigFlags defines the flags of cfg on fs, cfg is only used for its type.
+func BindConfigFlags(fs *flag.FlagSet, cfg interface{}) *ConfigFlags {
+	f := &ConfigFlags{
+		metas: configMetas(reflect.TypeOf(cfg), "", ""),
+		flags: make(map[string]*configFlag),
+	}
+	for _, meta := range f.metas {
+		fl := &configFlag{
+			name:  strings.ToLower(meta.Key),
+			value: meta.Default,
+			typ:   meta.Field.Type,
+		}
+		usage := meta.Field.Tag.Get(cleanenv.TagEnvDescription)
+		if len(meta.Env) > 0 {
+			usage = strings.TrimSpace(usage + " (env " + strings.Join(meta.Env, ", ") + ")")
+		}
+		fs.Var(fl, fl.name, usage)
+		f.flags[meta.Key] = fl
+	}
+	return f
+}
+
+// apply sets the values of the flags given on the command line.
+func (f *ConfigFlags) apply(cfg interface{}, report *ConfigReport) error {
+	values := make(map[string]interface{})
+	names := make(map[string]string)
+	for key, fl := range f.flags {
+		if fl.set {
+			values[key] = fl.value
+			names[key] = fl.name
+		}
+	}
+	tree := expandKeys(values)
+	err := applyTree(reflect.ValueOf(cfg), tree, "", func(key string) {
+		report.Origins[key] = "flag --" + names[key]
+	})
+	if err != nil {
+		return err
+	}
+	return report.applyDurations(cfg, tree)
+}
+
+// PrintTable writes every key with its flag, environment variables,
+// default and the source of its current value taken from report.
+func (f *ConfigFlags) PrintTable(w io.Writer, report *ConfigReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tFLAG\tENV\tDEFAULT\tSOURCE")
+	for _, meta := range f.metas {
+		source := "-"
+		if report != nil {
+			if origin, ok := report.Origins[meta.Key]; ok {
+				source = origin
+			}
+		}
+		env := strings.Join(meta.Env, ",")
+		if env == "" {
+			env = "-"
+		}
+		def := meta.Default
+		if !meta.HasDefault {
+			def = "-"
+		}
+		fmt.Fprintf(tw, "%s\t--%s\t%s\t%s\t%s\n", meta.Key, f.flags[meta.Key].name, env, def, source)
+	}
+	return tw.Flush()
+}
+
+func (fl *configFlag) String() string {
+	if fl == nil {
+		return ""
+	}
+	return fl.value
+}
+
+// Set checks s decodes into the field type, the field itself is set by apply.
+func (fl *configFlag) Set(s string) error {
+	if err := setConfigValue(reflect.New(fl.typ).Elem(), s); err != nil {
+		return err
+	}
+	fl.value, fl.set = s, true
+	return nil
+}
+
+func (fl *configFlag) IsBoolFlag() bool {
+	return fl.typ != nil && fl.typ.Kind() == reflect.Bool
+}
@@ -0,0 +1,66 @@
+package valkyrie
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type flagConfig struct {
+	App struct {
+		Name  string   `yaml:"name" env-default:"valkyrie"`
+		Port  int      `yaml:"port" env:"APP_PORT"`
+		Debug bool     `yaml:"debug"`
+		Hosts []string `yaml:"hosts"`
+	} `yaml:"App"`
+	DB struct {
+		DsnMain string `yaml:"dsn_main" env:"DSN_MAIN" env-description:"main database"`
+	}
+}
+
+func TestConfigFlags(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "app.yaml", "App:\n  port: 8778\n  hosts: [a]\n")
+	t.Setenv("APP_PORT", "8000")
+	t.Setenv("DSN_MAIN", "host=localhost")
+
+	var cfg flagConfig
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := BindConfigFlags(fs, &cfg)
+	assert.NoError(t, fs.Parse([]string{"--app.port=9000", "--app.debug", "--app.hosts=b,c"}))
+
+	report, err := LoadConfig(ConfigOpts{
+		Config:    &cfg,
+		Filenames: []string{"app.yaml"},
+		Paths:     []string{dir},
+		Flags:     flags,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 9000, cfg.App.Port)
+	assert.True(t, cfg.App.Debug)
+	assert.Equal(t, []string{"b", "c"}, cfg.App.Hosts)
+	assert.Equal(t, "valkyrie", cfg.App.Name)
+	assert.Equal(t, "host=localhost", cfg.DB.DsnMain)
+
+	var buf bytes.Buffer
+	assert.NoError(t, flags.PrintTable(&buf, report))
+	assert.Equal(t, `KEY          FLAG           ENV       DEFAULT   SOURCE
+App.name     --app.name     -         valkyrie  default
+App.port     --app.port     APP_PORT  -         flag --app.port
+App.debug    --app.debug    -         -         flag --app.debug
+App.hosts    --app.hosts    -         -         flag --app.hosts
+db.dsn_main  --db.dsn_main  DSN_MAIN  -         env DSN_MAIN
+`, buf.String())
+}
+
+func TestConfigFlagsInvalid(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	BindConfigFlags(fs, &flagConfig{})
+
+	assert.Error(t, fs.Parse([]string{"--app.port=nine"}))
+}
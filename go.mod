@@ -1,16 +1,32 @@
 module github.com/kubuskotak/valkyrie
 
-go 1.16
+go 1.17
 
 require (
-	github.com/go-playground/validator/v10 v10.9.0 // indirect
-	github.com/ilyakaznacheev/cleanenv v1.2.6 // indirect
-	github.com/oklog/ulid/v2 v2.0.2 // indirect
-	github.com/rs/zerolog v1.26.1 // indirect
-	github.com/stretchr/testify v1.7.0 // indirect
-	go.opentelemetry.io/otel v1.3.0 // indirect
-	go.opentelemetry.io/otel/exporters/jaeger v1.3.0 // indirect
-	go.opentelemetry.io/otel/sdk v1.3.0 // indirect
-	go.opentelemetry.io/otel/trace v1.3.0 // indirect
-	golang.org/x/crypto v0.0.0-20211215165025-cf75a172585e // indirect
+	github.com/BurntSushi/toml v0.3.1
+	github.com/go-playground/validator/v10 v10.9.0
+	github.com/ilyakaznacheev/cleanenv v1.2.6
+	github.com/oklog/ulid/v2 v2.0.2
+	github.com/rs/zerolog v1.26.1
+	github.com/stretchr/testify v1.7.0
+	go.opentelemetry.io/otel v1.3.0
+	go.opentelemetry.io/otel/exporters/jaeger v1.3.0
+	go.opentelemetry.io/otel/sdk v1.3.0
+	go.opentelemetry.io/otel/trace v1.3.0
+	golang.org/x/crypto v0.0.0-20211215165025-cf75a172585e
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
+	olympos.io/encoding/edn v0.0.0-20201019073823-d3554ca0b0a3
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logr/logr v1.2.1 // indirect
+	github.com/go-logr/stdr v1.2.0 // indirect
+	github.com/go-playground/locales v0.14.0 // indirect
+	github.com/go-playground/universal-translator v0.18.0 // indirect
+	github.com/joho/godotenv v1.3.0 // indirect
+	github.com/leodido/go-urn v1.2.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.0.0-20210809222454-d867a43fc93e // indirect
+	golang.org/x/text v0.3.6 // indirect
 )
@@ -0,0 +1,247 @@
+package valkyrie
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// InvalidNIK is wrapped by the errors of ParseNIK.
+var InvalidNIK = errors.New("invalid NIK")
+
+// NIK is a Nomor Induk Kependudukan, the 16-digit Indonesian identity number.
+type NIK struct {
+	// Province, Regency and District are the region codes, e.g. 32, 3273 and 327301.
+	Province string
+	Regency  string
+	District string
+
+	Birthdate time.Time
+	Female    bool
+	Serial    string
+}
+
+// indonesianTags holds the Indonesian rules registered by NewValidator, they check strings:
+//
+//	nik                    a NIK with a known province, a region and a birth date, see ParseNIK
+//	npwp                   a tax number of 15 or 16 digits, see NormalizeNPWP
+//	id_mobile              a mobile number such as 0812-3456-7890 or +62 812 3456 7890
+//	id_postal_code         a 5-digit postal code
+//	id_bank_account=bca    an account number of the length used by the bank, any bank without a parameter
+var indonesianTags = map[string]validator.Func{
+	"nik":             func(fl validator.FieldLevel) bool { return IsNIK(fl.Field().String()) },
+	"npwp":            func(fl validator.FieldLevel) bool { return IsNPWP(fl.Field().String()) },
+	"id_mobile":       func(fl validator.FieldLevel) bool { return IsMobile(fl.Field().String()) },
+	"id_postal_code":  func(fl validator.FieldLevel) bool { return IsPostalCode(fl.Field().String()) },
+	"id_bank_account": func(fl validator.FieldLevel) bool { return IsBankAccount(fl.Param(), fl.Field().String()) },
+}
+
+// nikProvinces are the province codes of NIK.
+var nikProvinces = map[string]bool{
+	"11": true, "12": true, "13": true, "14": true, "15": true, "16": true, "17": true, "18": true, "19": true, "21": true,
+	"31": true, "32": true, "33": true, "34": true, "35": true, "36": true,
+	"51": true, "52": true, "53": true,
+	"61": true, "62": true, "63": true, "64": true, "65": true,
+	"71": true, "72": true, "73": true, "74": true, "75": true, "76": true,
+	"81": true, "82": true,
+	"91": true, "92": true, "93": true, "94": true, "95": true, "96": true,
+}
+
+// mobilePrefixes are the operator prefixes of mobile numbers, after the leading 0 or +62.
+var mobilePrefixes = map[string]bool{
+	"811": true, "812": true, "813": true, "814": true, "815": true, "816": true, "817": true, "818": true, "819": true,
+	"821": true, "822": true, "823": true, "828": true,
+	"831": true, "832": true, "833": true, "838": true,
+	"851": true, "852": true, "853": true, "855": true, "856": true, "857": true, "858": true, "859": true,
+	"877": true, "878": true,
+	"881": true, "882": true, "883": true, "884": true, "885": true, "886": true, "887": true, "888": true, "889": true,
+	"895": true, "896": true, "897": true, "898": true, "899": true,
+}
+
+var (
+	bankAccountsMtx sync.RWMutex
+	// bankAccounts holds the account number lengths by bank, see RegisterBankAccount.
+	bankAccounts = map[string][]int{
+		"bca":     {10},
+		"bni":     {10},
+		"bri":     {15},
+		"mandiri": {13},
+		"cimb":    {13, 14},
+		"permata": {10},
+		"danamon": {10},
+		"bsi":     {10},
+		"btn":     {16},
+	}
+)
+
+// bankAccountMinDigits and bankAccountMaxDigits bound the account numbers of id_bank_account without a bank.
+const (
+	bankAccountMinDigits = 10
+	bankAccountMaxDigits = 16
+)
+
+// RegisterBankAccount sets the account number lengths of a bank for id_bank_account=bank.
+func RegisterBankAccount(bank string, digits ...int) {
+	bankAccountsMtx.Lock()
+	bankAccounts[strings.ToLower(bank)] = digits
+	bankAccountsMtx.Unlock()
+}
+
+// ParseNIK reads the region codes, birth date, sex and serial of a NIK. The day
+// of birth of women is increased by 40, and the year takes the latest century
+// that does not put the birth date in the future.
+func ParseNIK(s string) (NIK, error) {
+	if len(s) != 16 || !isDigits(s) {
+		return NIK{}, fmt.Errorf("%w: must have 16 digits", InvalidNIK)
+	}
+	if !nikProvinces[s[:2]] {
+		return NIK{}, fmt.Errorf("%w: unknown province %s", InvalidNIK, s[:2])
+	}
+	if s[2:4] == "00" || s[4:6] == "00" {
+		return NIK{}, fmt.Errorf("%w: unknown region %s", InvalidNIK, s[:6])
+	}
+	if s[12:] == "0000" {
+		return NIK{}, fmt.Errorf("%w: invalid serial %s", InvalidNIK, s[12:])
+	}
+
+	day, _ := strconv.Atoi(s[6:8])
+	month, _ := strconv.Atoi(s[8:10])
+	year, _ := strconv.Atoi(s[10:12])
+	female := day > 40
+	if female {
+		day -= 40
+	}
+	now := time.Now()
+	birthdate := time.Date(2000+year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if birthdate.After(now) {
+		birthdate = time.Date(1900+year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	}
+	if birthdate.Day() != day || int(birthdate.Month()) != month {
+		return NIK{}, fmt.Errorf("%w: invalid birth date %s", InvalidNIK, s[6:12])
+	}
+	return NIK{
+		Province:  s[:2],
+		Regency:   s[:4],
+		District:  s[:6],
+		Birthdate: birthdate,
+		Female:    female,
+		Serial:    s[12:],
+	}, nil
+}
+
+// IsNIK reports whether s is a valid NIK, see ParseNIK.
+func IsNIK(s string) bool {
+	_, err := ParseNIK(s)
+	return err == nil
+}
+
+// NormalizeNPWP returns the 16 digits of a tax number, the 15 digits of the
+// former format, plain or formatted as 01.234.567.8-901.000, are prefixed with 0.
+func NormalizeNPWP(s string) (string, bool) {
+	digits := s
+	if strings.ContainsAny(s, ".-") {
+		if !isNPWPFormat(s) {
+			return "", false
+		}
+		digits = strings.NewReplacer(".", "", "-", "").Replace(s)
+	}
+	if !isDigits(digits) {
+		return "", false
+	}
+	switch len(digits) {
+	case 15:
+		return "0" + digits, true
+	case 16:
+		return digits, true
+	}
+	return "", false
+}
+
+// IsNPWP reports whether s is a tax number, see NormalizeNPWP.
+func IsNPWP(s string) bool {
+	_, ok := NormalizeNPWP(s)
+	return ok
+}
+
+// isNPWPFormat matches 01.234.567.8-901.000.
+func isNPWPFormat(s string) bool {
+	const format = "99.999.999.9-999.999"
+	if len(s) != len(format) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if format[i] == '9' && (s[i] < '0' || s[i] > '9') || format[i] != '9' && s[i] != format[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeMobile returns a mobile number in the international form +628123456789,
+// s may start with 0, 62 or +62 and contain spaces, dashes, dots and parentheses.
+func NormalizeMobile(s string) (string, bool) {
+	s = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(s)
+	switch {
+	case strings.HasPrefix(s, "+62"):
+		s = s[3:]
+	case strings.HasPrefix(s, "62"):
+		s = s[2:]
+	case strings.HasPrefix(s, "0"):
+		s = s[1:]
+	default:
+		return "", false
+	}
+	// the numbers after the prefix have 9 to 12 digits, e.g. 812 3456 7890
+	if len(s) < 9 || len(s) > 12 || !isDigits(s) || !mobilePrefixes[s[:3]] {
+		return "", false
+	}
+	return "+62" + s, true
+}
+
+// IsMobile reports whether s is a mobile number, see NormalizeMobile.
+func IsMobile(s string) bool {
+	_, ok := NormalizeMobile(s)
+	return ok
+}
+
+// IsPostalCode reports whether s is a 5-digit postal code, from 10110 to 99999.
+func IsPostalCode(s string) bool {
+	return len(s) == 5 && isDigits(s) && s >= "10110"
+}
+
+// IsBankAccount reports whether s is an account number of bank, spaces and dashes
+// are ignored. An empty bank accepts 10 to 16 digits, an unknown bank nothing.
+func IsBankAccount(bank, s string) bool {
+	s = strings.NewReplacer(" ", "", "-", "").Replace(s)
+	if !isDigits(s) {
+		return false
+	}
+	if bank == "" {
+		return len(s) >= bankAccountMinDigits && len(s) <= bankAccountMaxDigits
+	}
+	bankAccountsMtx.RLock()
+	defer bankAccountsMtx.RUnlock()
+	for _, n := range bankAccounts[strings.ToLower(bank)] {
+		if len(s) == n {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package valkyrie
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type customerForm struct {
+	NIK        string `json:"nik" validate:"nik"`
+	NPWP       string `json:"npwp" validate:"omitempty,npwp"`
+	Phone      string `json:"phone" validate:"id_mobile"`
+	PostalCode string `json:"postal_code" validate:"id_postal_code"`
+	Account    string `json:"account" validate:"id_bank_account=bca"`
+}
+
+func TestParseNIK(t *testing.T) {
+	nik, err := ParseNIK("3273014508900001")
+	assert.NoError(t, err)
+	assert.Equal(t, NIK{
+		Province:  "32",
+		Regency:   "3273",
+		District:  "327301",
+		Birthdate: time.Date(1990, 8, 5, 0, 0, 0, 0, time.UTC),
+		Female:    true,
+		Serial:    "0001",
+	}, nik)
+
+	nik, err = ParseNIK("3173011708450002")
+	assert.NoError(t, err)
+	assert.False(t, nik.Female)
+	assert.Equal(t, 1945, nik.Birthdate.Year())
+
+	// a birth date later in the current year belongs to the previous century
+	now := time.Now().UTC()
+	for days, century := range map[int]int{-1: 0, 1: 100} {
+		born := now.AddDate(0, 0, days)
+		if born.Month() == time.February && born.Day() == 29 {
+			// not a date in most years of the previous century
+			born = born.AddDate(0, 0, days)
+		}
+		nik, err = ParseNIK("327301" + born.Format("020106") + "0001")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(born.Year()-century, born.Month(), born.Day(), 0, 0, 0, 0, time.UTC), nik.Birthdate)
+	}
+
+	for _, s := range []string{
+		"327301450890001",  // 15 digits
+		"32730145089000a1", // not a digit
+		"2073014508900001", // unknown province
+		"3200014508900001", // unknown regency
+		"3273013102900001", // 31 February
+		"3273017208900001", // 32 + 40
+		"3273014508900000", // serial
+	} {
+		_, err := ParseNIK(s)
+		assert.True(t, errors.Is(err, InvalidNIK), s)
+	}
+}
+
+func TestNormalizeNPWP(t *testing.T) {
+	for s, want := range map[string]string{
+		"01.234.567.8-901.000": "0012345678901000",
+		"012345678901000":      "0012345678901000",
+		"3273014508900001":     "3273014508900001",
+	} {
+		got, ok := NormalizeNPWP(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, want, got)
+	}
+	for _, s := range []string{"01.234.567.8901.000", "01-234-567-8-901-000", "0123456789010", "01234567890100a"} {
+		assert.False(t, IsNPWP(s), s)
+	}
+}
+
+func TestNormalizeMobile(t *testing.T) {
+	for _, s := range []string{"081234567890", "0812-3456-7890", "+62 812 3456 7890", "6281234567890", "(0857) 1234.5678"} {
+		got, ok := NormalizeMobile(s)
+		assert.True(t, ok, s)
+		assert.Contains(t, []string{"+6281234567890", "+6285712345678"}, got)
+	}
+	for _, s := range []string{"0212345678", "81234567890", "0812345", "08123456789012", "+1 812 3456 7890"} {
+		assert.False(t, IsMobile(s), s)
+	}
+}
+
+func TestIndonesianTags(t *testing.T) {
+	assert.Nil(t, Validate(customerForm{
+		NIK:        "3273014508900001",
+		Phone:      "0812-3456-7890",
+		PostalCode: "40115",
+		Account:    "123-456-7890",
+	}))
+
+	errs := Validate(customerForm{NIK: "3273013102900001", NPWP: "1234", Phone: "021-123456", PostalCode: "01234", Account: "12345"})
+	assert.Equal(t, []string{
+		"nik must be a valid NIK",
+		"npwp must be a valid NPWP",
+		"phone must be an Indonesian mobile number, e.g. 0812-3456-7890",
+		"postal_code must be a 5-digit postal code",
+		"account must be a valid bank account number",
+	}, errorMessages(errs))
+
+	errs = ValidateCtx(WithLocale(context.Background(), "id"), customerForm{NIK: "3273014508900001", Phone: "081234567890", PostalCode: "40115", Account: "1"})
+	assert.Equal(t, []string{"account harus berupa nomor rekening bank yang valid"}, errorMessages(errs))
+
+	assert.Nil(t, ValidateVar("123456789012345", "id_bank_account=BRI"))
+	assert.Nil(t, ValidateVar("1234567890", "id_bank_account"))
+	assert.Len(t, ValidateVar("1234567890", "id_bank_account=unknown"), 1)
+
+	RegisterBankAccount("jago", 12)
+	defer func() {
+		bankAccountsMtx.Lock()
+		delete(bankAccounts, "jago")
+		bankAccountsMtx.Unlock()
+	}()
+	assert.Nil(t, ValidateVar("123456789012", "id_bank_account=jago"))
+}
@@ -0,0 +1,283 @@
+package valkyrie
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	includeTag = "!include"
+	includeKey = "$include"
+)
+
+// variableRegex matches ${NAME}, ${NAME:-default} and the $${ escape.
+var variableRegex = regexp.MustCompile(`\$\$\{|\$\{([^}:]+)(?::-([^}]*))?\}`)
+
+// readConfigFile reads a config file and returns a decoder for its content.
+//
+// YAML files are preprocessed before decoding:
+//
+//	database: !include database.yaml   replaces the value with the file content
+//	$include: [base.yaml, other.yaml]  merges the files into the mapping, local keys win
+//	host: ${DB_HOST:-localhost}        interpolates an environment variable with a default
+//	dsn: postgres://${db.host}:5432    interpolates another key of the same file
+//
+// Includes are relative to the including file, keys take precedence over
+// environment variables looked up with lookupEnv and $${ escapes a literal ${.
+// The included files are returned so they can be watched.
+func readConfigFile(fp string, lookupEnv func(string) (string, bool)) (decode func(v interface{}) error, includes []string, err error) {
+	ext := strings.ToLower(filepath.Ext(fp))
+	if ext != ".yaml" && ext != ".yml" {
+		b, err := os.ReadFile(fp)
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(v interface{}) error {
+			return decodeConfig(bytes.NewReader(b), ext, v)
+		}, nil, nil
+	}
+
+	p := &yamlPreprocessor{lookupEnv: lookupEnv}
+	root, err := p.include(fp, nil)
+	if err == nil && root != nil {
+		err = p.interpolate(root, root, nil)
+	}
+	if err != nil {
+		return nil, p.includes, fmt.Errorf("config file %s parsing error: %w", fp, err)
+	}
+	return func(v interface{}) error {
+		if root == nil {
+			return nil
+		}
+		return root.Decode(v)
+	}, p.includes, nil
+}
+
+type yamlPreprocessor struct {
+	lookupEnv func(string) (string, bool)
+	includes  []string
+	// expanded holds the scalars already interpolated, so escaped $${ stay literal
+	expanded map[*yaml.Node]bool
+}
+
+// include parses fp and resolves its includes, stack holds the including files.
+func (p *yamlPreprocessor) include(fp string, stack []string) (*yaml.Node, error) {
+	abs, err := filepath.Abs(fp)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range stack {
+		if s == abs {
+			return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), abs)
+		}
+	}
+	b, err := os.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err = yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	return root, p.resolve(root, filepath.Dir(fp), append(stack, abs))
+}
+
+func (p *yamlPreprocessor) includeFile(dir, name string, stack []string) (*yaml.Node, error) {
+	fp := name
+	if !filepath.IsAbs(fp) {
+		fp = filepath.Join(dir, name)
+	}
+	n, err := p.include(fp, stack)
+	if err != nil {
+		return nil, err
+	}
+	p.includes = append(p.includes, fp)
+	if n == nil {
+		n = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	return n, nil
+}
+
+// resolve replaces the !include values and merges the $include keys below n.
+func (p *yamlPreprocessor) resolve(n *yaml.Node, dir string, stack []string) error {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if n.Tag == includeTag {
+			inc, err := p.includeFile(dir, n.Value, stack)
+			if err != nil {
+				return err
+			}
+			*n = *inc
+		}
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			if err := p.resolve(c, dir, stack); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		var names []*yaml.Node
+		local := &yaml.Node{Kind: yaml.MappingNode, Tag: n.Tag}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.Value == includeKey {
+				if v.Kind == yaml.SequenceNode {
+					names = append(names, v.Content...)
+				} else {
+					names = append(names, v)
+				}
+				continue
+			}
+			if err := p.resolve(v, dir, stack); err != nil {
+				return err
+			}
+			local.Content = append(local.Content, k, v)
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, name := range names {
+			inc, err := p.includeFile(dir, name.Value, stack)
+			if err != nil {
+				return err
+			}
+			if inc.Kind != yaml.MappingNode {
+				return fmt.Errorf("%s %s is not a mapping", includeKey, name.Value)
+			}
+			mergeYAML(merged, inc)
+		}
+		mergeYAML(merged, local)
+		n.Content = merged.Content
+	}
+	return nil
+}
+
+// mergeYAML deep-merges the mapping src into dst, values of src win.
+func mergeYAML(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		k, v := src.Content[i], src.Content[i+1]
+		found := false
+		for j := 0; j+1 < len(dst.Content); j += 2 {
+			if dst.Content[j].Value != k.Value {
+				continue
+			}
+			if dst.Content[j+1].Kind == yaml.MappingNode && v.Kind == yaml.MappingNode {
+				mergeYAML(dst.Content[j+1], v)
+			} else {
+				dst.Content[j+1] = v
+			}
+			found = true
+			break
+		}
+		if !found {
+			dst.Content = append(dst.Content, k, v)
+		}
+	}
+}
+
+// interpolate expands the variables of every scalar below n,
+// stack holds the keys being expanded to detect cycles.
+func (p *yamlPreprocessor) interpolate(root, n *yaml.Node, stack []string) error {
+	if n.Kind != yaml.ScalarNode {
+		for _, c := range n.Content {
+			if err := p.interpolate(root, c, stack); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if p.expanded[n] || !strings.Contains(n.Value, "${") {
+		return nil
+	}
+	var (
+		out  strings.Builder
+		last int
+	)
+	for _, m := range variableRegex.FindAllStringSubmatchIndex(n.Value, -1) {
+		out.WriteString(n.Value[last:m[0]])
+		last = m[1]
+		if m[2] < 0 {
+			// $${ escape
+			out.WriteString("${")
+			continue
+		}
+		name := n.Value[m[2]:m[3]]
+		v, err := p.lookup(root, name, stack)
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			if m[4] < 0 {
+				return fmt.Errorf("variable %s is not set", name)
+			}
+			def := n.Value[m[4]:m[5]]
+			v = &def
+		}
+		out.WriteString(*v)
+	}
+	out.WriteString(n.Value[last:])
+	n.Value = out.String()
+	if p.expanded == nil {
+		p.expanded = make(map[*yaml.Node]bool)
+	}
+	p.expanded[n] = true
+	if n.Style == 0 {
+		// let yaml resolve the type of the expanded plain value, e.g. a port number
+		n.Tag = ""
+	}
+	return nil
+}
+
+// lookup resolves a variable from the keys of the document, then from the environment.
+func (p *yamlPreprocessor) lookup(root *yaml.Node, name string, stack []string) (*string, error) {
+	if n := yamlPath(root, strings.Split(name, ".")); n != nil {
+		if n.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("variable %s is not a scalar", name)
+		}
+		for _, s := range stack {
+			if s == name {
+				return nil, fmt.Errorf("variable cycle: %s -> %s", strings.Join(stack, " -> "), name)
+			}
+		}
+		if err := p.interpolate(root, n, append(stack, name)); err != nil {
+			return nil, err
+		}
+		return &n.Value, nil
+	}
+	if v, ok := p.lookupEnv(name); ok {
+		return &v, nil
+	}
+	return nil, nil
+}
+
+// yamlPath returns the value node at the dot separated key path, matching keys exactly.
+func yamlPath(n *yaml.Node, path []string) *yaml.Node {
+	for _, key := range path {
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				next = n.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
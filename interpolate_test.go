@@ -0,0 +1,86 @@
+package valkyrie
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type interpolateConfig struct {
+	App struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+		URL  string `yaml:"url"`
+		Name string `yaml:"name"`
+		Note string `yaml:"note"`
+	} `yaml:"App"`
+	DB struct {
+		Host string `yaml:"host"`
+		User string `yaml:"user"`
+		Pool int    `yaml:"pool"`
+	} `yaml:"db"`
+}
+
+func TestConfigInterpolate(t *testing.T) {
+	dir := t.TempDir()
+	fp := writeConfig(t, dir, "app.yaml", `App:
+  host: ${APP_HOST:-localhost}
+  port: ${APP_PORT}
+  url: http://${App.host}:${App.port}
+  note: "$${literal}"
+db: !include db/database.yaml
+`)
+	inc := writeConfig(t, filepath.Join(dir, "db"), "database.yaml", "$include: defaults.yaml\nhost: ${DB_HOST:-db}\nuser: root\n")
+	defaults := writeConfig(t, filepath.Join(dir, "db"), "defaults.yaml", "user: nobody\npool: 10\n")
+	t.Setenv("APP_PORT", "8778")
+
+	var cfg interpolateConfig
+	report, err := LoadConfig(ConfigOpts{
+		Config:    &cfg,
+		Filenames: []string{"app.yaml"},
+		Paths:     []string{dir},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost", cfg.App.Host)
+	assert.Equal(t, 8778, cfg.App.Port)
+	assert.Equal(t, "http://localhost:8778", cfg.App.URL)
+	assert.Equal(t, "${literal}", cfg.App.Note)
+	assert.Equal(t, "db", cfg.DB.Host)
+	assert.Equal(t, "root", cfg.DB.User)
+	assert.Equal(t, 10, cfg.DB.Pool)
+	assert.Equal(t, []string{fp}, report.Files)
+	assert.ElementsMatch(t, []string{inc, defaults}, report.Includes)
+	assert.Equal(t, fp, report.Origins["db.pool"])
+}
+
+func TestConfigInterpolateFail(t *testing.T) {
+	tt := []struct {
+		name  string
+		files map[string]string
+		err   string
+	}{
+		{"unset variable", map[string]string{"app.yaml": "App:\n  host: ${VALKYRIE_UNSET}\n"}, "variable VALKYRIE_UNSET is not set"},
+		{"variable cycle", map[string]string{"app.yaml": "App:\n  host: ${App.name}\n  name: ${App.host}\n"}, "variable cycle"},
+		{"include cycle", map[string]string{"app.yaml": "$include: other.yaml\n", "other.yaml": "$include: app.yaml\n"}, "include cycle"},
+		{"missing include", map[string]string{"app.yaml": "db: !include missing.yaml\n"}, "missing.yaml"},
+	}
+	for _, c := range tt {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range c.files {
+				writeConfig(t, dir, name, content)
+			}
+			var cfg interpolateConfig
+			err := Config(ConfigOpts{
+				Config:    &cfg,
+				Filenames: []string{"app.yaml"},
+				Paths:     []string{dir},
+			})
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), c.err)
+		})
+	}
+}
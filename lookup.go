@@ -0,0 +1,216 @@
+package valkyrie
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// DefaultLookupTimeout bounds every call of a context-aware rule, see WithLookupTimeout.
+var DefaultLookupTimeout = 3 * time.Second
+
+// lookupFailedTag is the message template of rules whose lookup returned an error.
+const lookupFailedTag = "lookup_failed"
+
+type (
+	// Lookup answers whether a value is present in a data set such as a table column.
+	Lookup interface {
+		Exists(ctx context.Context, value interface{}) (bool, error)
+	}
+
+	// LookupFunc adapts a function to Lookup.
+	LookupFunc func(ctx context.Context, value interface{}) (bool, error)
+
+	// ContextFunc is a rule that needs I/O, an error fails the rule and is kept
+	// in the ErrorValidator so errors.Is(errs.Err(), context.DeadlineExceeded) works.
+	ContextFunc func(ctx context.Context, fl validator.FieldLevel) (bool, error)
+
+	// MemoryLookup is an in-memory Lookup, values are compared by their fmt.Sprint form.
+	MemoryLookup struct {
+		mtx    sync.RWMutex
+		values map[string]struct{}
+	}
+
+	// lookupState records the failures of the context-aware rules of a validation.
+	lookupState struct {
+		mtx      sync.Mutex
+		failures map[lookupFailure][]error
+	}
+
+	// lookupFailure identifies a failed context-aware rule by the struct field,
+	// e.g. Tags[1] for dive, tag, parameter and value of the reported error.
+	lookupFailure struct {
+		field, tag, param, value string
+	}
+
+	lookupStateKey struct{}
+)
+
+func (f LookupFunc) Exists(ctx context.Context, value interface{}) (bool, error) {
+	return f(ctx, value)
+}
+
+// NewMemoryLookup returns a MemoryLookup holding values.
+func NewMemoryLookup(values ...interface{}) *MemoryLookup {
+	m := &MemoryLookup{values: make(map[string]struct{})}
+	m.Add(values...)
+	return m
+}
+
+// Add stores values.
+func (m *MemoryLookup) Add(values ...interface{}) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for _, v := range values {
+		m.values[fmt.Sprint(v)] = struct{}{}
+	}
+}
+
+// Remove deletes values.
+func (m *MemoryLookup) Remove(values ...interface{}) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for _, v := range values {
+		delete(m.values, fmt.Sprint(v))
+	}
+}
+
+func (m *MemoryLookup) Exists(ctx context.Context, value interface{}) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	_, ok := m.values[fmt.Sprint(value)]
+	return ok, nil
+}
+
+// WithLookup registers a data set, see Validator.RegisterLookup.
+func WithLookup(name string, l Lookup) ValidatorOption {
+	return func(v *Validator) {
+		v.RegisterLookup(name, l)
+	}
+}
+
+// WithLookupTimeout bounds every call of a context-aware rule, 0 disables the timeout.
+func WithLookupTimeout(d time.Duration) ValidatorOption {
+	return func(v *Validator) {
+		v.lookupTimeout = d
+	}
+}
+
+// RegisterLookup makes the data set l available to the tags exists=name,
+// the value must be present, and unique=name, the value must be absent.
+func (v *Validator) RegisterLookup(name string, l Lookup) {
+	v.lookups[name] = l
+}
+
+// RegisterValidationCtx adds a context-aware tag. Every call gets its own
+// timeout and tracing span, the ctx given to ValidateCtx is its parent.
+func (v *Validator) RegisterValidationCtx(tag string, fn ContextFunc) error {
+	v.ctxTags[tag] = true
+	return v.validate.RegisterValidationCtx(tag, func(ctx context.Context, fl validator.FieldLevel) bool {
+		ok, err := v.runLookup(ctx, tag, fl, fn)
+		if !ok {
+			if state, _ := ctx.Value(lookupStateKey{}).(*lookupState); state != nil {
+				key := lookupFailure{field: fl.StructFieldName(), tag: tag, param: fl.Param(), value: fmt.Sprint(fl.Field().Interface())}
+				state.mtx.Lock()
+				if state.failures == nil {
+					state.failures = make(map[lookupFailure][]error)
+				}
+				state.failures[key] = append(state.failures[key], err)
+				state.mtx.Unlock()
+			}
+		}
+		return ok
+	})
+}
+
+func (v *Validator) runLookup(ctx context.Context, tag string, fl validator.FieldLevel, fn ContextFunc) (bool, error) {
+	if v.lookupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, v.lookupTimeout)
+		defer cancel()
+	}
+	ctx, span := otel.Tracer("validator::lookup").Start(ctx, "validate "+tag)
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("validate.tag", tag),
+		attribute.String("validate.param", fl.Param()),
+		attribute.String("validate.field", fl.FieldName()),
+	)
+
+	ok, err := fn(ctx, fl)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "lookup")
+		return false, err
+	}
+	span.SetAttributes(attribute.Bool("validate.valid", ok))
+	return ok, nil
+}
+
+// lookupRule checks the value against the data set named by the tag parameter.
+func (v *Validator) lookupRule(want bool) ContextFunc {
+	return func(ctx context.Context, fl validator.FieldLevel) (bool, error) {
+		l, ok := v.lookups[fl.Param()]
+		if !ok {
+			return false, fmt.Errorf("lookup %s is not registered", fl.Param())
+		}
+		found, err := l.Exists(ctx, fl.Field().Interface())
+		if err != nil {
+			return false, err
+		}
+		return found == want, nil
+	}
+}
+
+// ValidateCtx checks the validate tags of s with DefaultValidator.
+func ValidateCtx(ctx context.Context, s interface{}) ValidationErrors {
+	return DefaultValidator.ValidateCtx(ctx, s)
+}
+
+// attachLookupFailures keeps the lookup errors in the matching errs. A failure
+// is matched by the field, tag, parameter and value of the error, failures of
+// or-tags such as exists=a|unique=b are looked up for every alternative.
+func (v *Validator) attachLookupFailures(errs ValidationErrors, verrs validator.ValidationErrors, state *lookupState, locale string) {
+	for i, verr := range verrs {
+		var failure error
+		rules := strings.Split(verr.ActualTag(), "|")
+		for _, rule := range rules {
+			// the tag of an or-tag error holds the parameters, e.g. exists=a|unique=b
+			parts := strings.SplitN(rule, "=", 2)
+			if !v.ctxTags[parts[0]] {
+				continue
+			}
+			key := lookupFailure{field: verr.StructField(), tag: parts[0], param: verr.Param(), value: fmt.Sprint(verr.Value())}
+			if len(rules) > 1 {
+				key.param = ""
+				if len(parts) == 2 {
+					key.param = parts[1]
+				}
+			}
+			failures := state.failures[key]
+			if len(failures) == 0 {
+				continue
+			}
+			state.failures[key] = failures[1:]
+			if failure == nil {
+				failure = failures[0]
+			}
+		}
+		if failure != nil {
+			errs[i].err = failure
+			failed := errs[i]
+			failed.Tag = lookupFailedTag
+			errs[i].Message = errorMessage(locale, failed)
+		}
+	}
+}
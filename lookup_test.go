@@ -0,0 +1,114 @@
+package valkyrie
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type productForm struct {
+	Name       string `json:"name" validate:"required"`
+	Email      string `json:"email" validate:"email,unique=users.email"`
+	CategoryID int    `json:"category_id" validate:"exists=categories"`
+	Tags       []int  `json:"tags" validate:"dive,exists=categories"`
+}
+
+func TestValidateCtx(t *testing.T) {
+	users := NewMemoryLookup("taken@mail.com")
+	v := NewValidator(
+		WithLookup("users.email", users),
+		WithLookup("categories", NewMemoryLookup(1, 2, 3)),
+	)
+
+	ctx := context.Background()
+	assert.Nil(t, v.ValidateCtx(ctx, productForm{Name: "kopi", Email: "new@mail.com", CategoryID: 2, Tags: []int{1, 3}}))
+
+	errs := v.ValidateCtx(ctx, productForm{Email: "taken@mail.com", CategoryID: 9, Tags: []int{1, 4}})
+	assert.Equal(t, []string{
+		"name is required",
+		"email taken@mail.com is already taken",
+		"category_id 9 does not exist",
+		"tags[1] 4 does not exist",
+	}, errorMessages(errs))
+	assert.Equal(t, "/tags/1", errs[3].Pointer)
+
+	users.Remove("taken@mail.com")
+	assert.Nil(t, v.ValidateCtx(ctx, productForm{Name: "kopi", Email: "taken@mail.com", CategoryID: 1}))
+
+	errs = v.ValidateCtx(WithLocale(ctx, "id"), productForm{Name: "kopi", Email: "a@mail.com", CategoryID: 9})
+	assert.Equal(t, []string{"category_id 9 tidak ditemukan"}, errorMessages(errs))
+
+	assert.Len(t, v.ValidateVar(9, "exists=categories"), 1)
+	assert.Nil(t, v.ValidateVar(3, "exists=categories"))
+}
+
+func TestValidateCtxLookupError(t *testing.T) {
+	slow := LookupFunc(func(ctx context.Context, value interface{}) (bool, error) {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(time.Second):
+			return true, nil
+		}
+	})
+	v := NewValidator(WithLookup("categories", slow), WithLookupTimeout(10*time.Millisecond))
+
+	errs := v.ValidateCtx(context.Background(), productForm{Name: "kopi", Email: "a@mail.com", CategoryID: 1})
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "email", errs[0].Field)
+	assert.Equal(t, "unique", errs[0].Tag)
+	assert.Contains(t, errs[0].Unwrap().Error(), "lookup users.email is not registered")
+	assert.Equal(t, "category_id could not be checked, please try again", errs[1].Message)
+	assert.True(t, errors.Is(errs.Err(), context.DeadlineExceeded))
+	assert.False(t, errors.Is(errs.Err(), NotStruct))
+}
+
+func TestValidateCtxLookupOr(t *testing.T) {
+	type routeForm struct {
+		Code   string `json:"code" validate:"exists=replica|exists=codes"`
+		Region int    `json:"region" validate:"exists=regions"`
+	}
+	down := errors.New("replica is down")
+	v := NewValidator(
+		WithLookup("replica", LookupFunc(func(ctx context.Context, value interface{}) (bool, error) { return false, down })),
+		WithLookup("codes", NewMemoryLookup("CGK")),
+		WithLookup("regions", NewMemoryLookup(1)),
+	)
+
+	// code passes with its second rule, its failed lookup is not attached to region
+	errs := v.ValidateCtx(context.Background(), routeForm{Code: "CGK", Region: 9})
+	assert.Equal(t, []string{"region 9 does not exist"}, errorMessages(errs))
+	assert.Nil(t, errs[0].Unwrap())
+
+	errs = v.ValidateCtx(context.Background(), routeForm{Code: "SUB", Region: 9})
+	assert.Len(t, errs, 2)
+	assert.True(t, errors.Is(errs[0].Unwrap(), down))
+	assert.Equal(t, "code could not be checked, please try again", errs[0].Message)
+	assert.Equal(t, "region 9 does not exist", errs[1].Message)
+}
+
+func TestValidateCtxTrace(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	defer otel.SetTracerProvider(prev)
+
+	v := NewValidator(WithLookup("users.email", NewMemoryLookup()), WithLookup("categories", NewMemoryLookup(1)))
+	ctx, span := otel.Tracer("test").Start(context.Background(), "create product")
+	assert.Nil(t, v.ValidateCtx(ctx, productForm{Name: "kopi", Email: "a@mail.com", CategoryID: 1}))
+	span.End()
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 3)
+	assert.Equal(t, "validate unique", spans[0].Name())
+	assert.Equal(t, span.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("validate.param", "users.email"))
+	assert.Contains(t, spans[1].Attributes(), attribute.Bool("validate.valid", true))
+}
@@ -0,0 +1,373 @@
+package valkyrie
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// LocaleEN is the English message catalogue.
+	LocaleEN = "en"
+	// LocaleID is the Indonesian message catalogue.
+	LocaleID = "id"
+
+	// defaultMessage is the template of tags without one of their own.
+	defaultMessage = "default"
+	// fieldName is the {field} of values validated without a name, see ValidateVar.
+	fieldName = "field"
+)
+
+// DefaultLocale is used when no locale is selected or the selected one has no catalogue.
+var DefaultLocale = LocaleEN
+
+var (
+	messagesMtx sync.RWMutex
+	// messages holds the templates by locale and tag, {field}, {param} and {value}
+	// are replaced with the field name, the tag parameter and the invalid value.
+	// Date tags also get {format} and {example} of their layout, and {min} and {max}.
+	messages = map[string]map[string]string{
+		LocaleEN: {
+			defaultMessage:    "Invalid Type {value} for input {field}",
+			fieldName:         "value",
+			"required":        "{field} is required",
+			"email":           "{field} must be a valid email address",
+			"url":             "{field} must be a valid URL",
+			"uri":             "{field} must be a valid URI",
+			"uuid":            "{field} must be a valid UUID",
+			"numeric":         "{field} must be a number",
+			"alpha":           "{field} must contain letters only",
+			"alphanum":        "{field} must contain letters and numbers only",
+			"len":             "{field} must have a length of {param}",
+			"min":             "{field} must be at least {param}",
+			"max":             "{field} must be at most {param}",
+			"eq":              "{field} must be equal to {param}",
+			"ne":              "{field} must not be equal to {param}",
+			"gt":              "{field} must be greater than {param}",
+			"gte":             "{field} must be greater than or equal to {param}",
+			"lt":              "{field} must be less than {param}",
+			"lte":             "{field} must be less than or equal to {param}",
+			"eqfield":         "{field} must be equal to {param}",
+			"oneof":           "{field} must be one of: {param}",
+			"enum":            "{field} must be one of: {param}",
+			"enum_strict":     "{field} must be one of: {param}",
+			"date":            "{field} must be a date formatted as {format}, e.g. {example}",
+			"datetime":        "{field} must be a date and time formatted as {format}, e.g. {example}",
+			"daterange":       "{field} must be a date between {min} and {max}",
+			"past":            "{field} must be a date in the past",
+			"future":          "{field} must be a date in the future",
+			"age_min":         "{field} must be a birth date of someone at least {param} years old",
+			"age_max":         "{field} must be a birth date of someone at most {param} years old",
+			"after_field":     "{field} must be after {param}",
+			"before_field":    "{field} must be before {param}",
+			"nik":             "{field} must be a valid NIK",
+			"npwp":            "{field} must be a valid NPWP",
+			"id_mobile":       "{field} must be an Indonesian mobile number, e.g. 0812-3456-7890",
+			"id_postal_code":  "{field} must be a 5-digit postal code",
+			"id_bank_account": "{field} must be a valid bank account number",
+			notStructTag:      "cannot validate {value}, it must be a struct or a pointer to a struct",
+			"exists":          "{field} {value} does not exist",
+			"unique":          "{field} {value} is already taken",
+			lookupFailedTag:   "{field} could not be checked, please try again",
+		},
+		LocaleID: {
+			defaultMessage:    "{field} tidak valid",
+			fieldName:         "nilai",
+			"required":        "{field} wajib diisi",
+			"email":           "{field} harus berupa alamat email yang valid",
+			"url":             "{field} harus berupa URL yang valid",
+			"uri":             "{field} harus berupa URI yang valid",
+			"uuid":            "{field} harus berupa UUID yang valid",
+			"numeric":         "{field} harus berupa angka",
+			"alpha":           "{field} hanya boleh berisi huruf",
+			"alphanum":        "{field} hanya boleh berisi huruf dan angka",
+			"len":             "{field} harus memiliki panjang {param}",
+			"min":             "{field} minimal {param}",
+			"max":             "{field} maksimal {param}",
+			"eq":              "{field} harus sama dengan {param}",
+			"ne":              "{field} tidak boleh sama dengan {param}",
+			"gt":              "{field} harus lebih besar dari {param}",
+			"gte":             "{field} harus lebih besar dari atau sama dengan {param}",
+			"lt":              "{field} harus lebih kecil dari {param}",
+			"lte":             "{field} harus lebih kecil dari atau sama dengan {param}",
+			"eqfield":         "{field} harus sama dengan {param}",
+			"oneof":           "{field} harus salah satu dari: {param}",
+			"enum":            "{field} harus salah satu dari: {param}",
+			"enum_strict":     "{field} harus salah satu dari: {param}",
+			"date":            "{field} harus berupa tanggal dengan format {format}, contoh {example}",
+			"datetime":        "{field} harus berupa tanggal dan waktu dengan format {format}, contoh {example}",
+			"daterange":       "{field} harus berupa tanggal antara {min} dan {max}",
+			"past":            "{field} harus berupa tanggal yang sudah lewat",
+			"future":          "{field} harus berupa tanggal yang akan datang",
+			"age_min":         "{field} harus berupa tanggal lahir dengan usia minimal {param} tahun",
+			"age_max":         "{field} harus berupa tanggal lahir dengan usia maksimal {param} tahun",
+			"after_field":     "{field} harus setelah {param}",
+			"before_field":    "{field} harus sebelum {param}",
+			"nik":             "{field} harus berupa NIK yang valid",
+			"npwp":            "{field} harus berupa NPWP yang valid",
+			"id_mobile":       "{field} harus berupa nomor ponsel Indonesia, contoh 0812-3456-7890",
+			"id_postal_code":  "{field} harus berupa kode pos 5 digit",
+			"id_bank_account": "{field} harus berupa nomor rekening bank yang valid",
+			notStructTag:      "tidak dapat memvalidasi {value}, harus berupa struct atau pointer ke struct",
+			"exists":          "{field} {value} tidak ditemukan",
+			"unique":          "{field} {value} sudah digunakan",
+			lookupFailedTag:   "{field} tidak dapat diperiksa, silakan coba lagi",
+		},
+	}
+)
+
+// layoutNames spells the elements of a time layout for messages.
+var layoutNames = strings.NewReplacer("2006", "YYYY", "01", "MM", "02", "DD", "15", "HH", "04", "mm", "05", "ss")
+
+// fieldMessages caches the tag messages of fields by fieldMessagesKey.
+var fieldMessages sync.Map
+
+// validateMsgRegex finds the tag=message pairs of a validate_msg tag,
+// messages may contain commas unless followed by another tag=.
+var validateMsgRegex = regexp.MustCompile(`(?:^|,)\s*([A-Za-z0-9_]+)=`)
+
+type (
+	localeKey struct{}
+
+	// fieldMessagesKey is a field by the struct type declaring it,
+	// so indexes and map keys of the validated values add no entries.
+	fieldMessagesKey struct {
+		owner reflect.Type
+		field string
+	}
+
+	// fieldMessage holds the msg tag and the validate_msg templates by tag of a field.
+	fieldMessage struct {
+		msg   string
+		byTag map[string]string
+	}
+)
+
+// RegisterMessages adds or replaces the templates of a locale by tag,
+// the "default" template is used for tags without one.
+func RegisterMessages(locale string, templates map[string]string) {
+	messagesMtx.Lock()
+	defer messagesMtx.Unlock()
+	locale = strings.ToLower(locale)
+	if messages[locale] == nil {
+		messages[locale] = make(map[string]string)
+	}
+	for tag, tmpl := range templates {
+		messages[locale][tag] = tmpl
+	}
+}
+
+// WithLocale returns a context selecting the message locale, lang is a locale
+// such as "id" or an Accept-Language header such as "id-ID,id;q=0.9,en;q=0.8".
+func WithLocale(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, localeKey{}, MatchLocale(lang))
+}
+
+// LocaleFromContext returns the locale selected with WithLocale, DefaultLocale otherwise.
+func LocaleFromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey{}).(string); ok {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// MatchLocale returns the preferred locale of an Accept-Language header
+// that has a catalogue, DefaultLocale when none has.
+func MatchLocale(acceptLanguage string) string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+	var langs []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		w := weighted{locale: strings.ToLower(strings.TrimSpace(fields[0])), q: 1}
+		for _, f := range fields[1:] {
+			if q := strings.TrimSpace(f); strings.HasPrefix(q, "q=") {
+				w.q, _ = strconv.ParseFloat(q[2:], 64)
+			}
+		}
+		if w.locale != "" && w.q > 0 {
+			langs = append(langs, w)
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	messagesMtx.RLock()
+	defer messagesMtx.RUnlock()
+	for _, l := range langs {
+		if _, ok := messages[l.locale]; ok {
+			return l.locale
+		}
+		base := strings.SplitN(l.locale, "-", 2)[0]
+		if _, ok := messages[base]; ok {
+			return base
+		}
+	}
+	return DefaultLocale
+}
+
+// Translate returns errs with their messages in the locale of ctx, rendered from the
+// catalogue. Prefer ValidateCtx, it keeps the messages declared in struct tags.
+func Translate(ctx context.Context, errs []ErrorValidator) ValidationErrors {
+	if errs == nil {
+		return nil
+	}
+	locale := LocaleFromContext(ctx)
+	translated := make(ValidationErrors, len(errs))
+	for i, ev := range errs {
+		ev.Message = errorMessage(locale, ev)
+		translated[i] = ev
+	}
+	return translated
+}
+
+// errorMessage renders the template of ev.Tag in locale.
+func errorMessage(locale string, ev ErrorValidator) string {
+	messagesMtx.RLock()
+	defer messagesMtx.RUnlock()
+	if ev.Field == "" {
+		ev.Field = messages[DefaultLocale][fieldName]
+		if name, ok := messages[locale][fieldName]; ok {
+			ev.Field = name
+		}
+	}
+	return renderMessage(lookupMessage(locale, ev.Tag), ev)
+}
+
+// lookupMessage returns the template of tag in locale, falling back to the default
+// template of locale, then to the templates of DefaultLocale.
+func lookupMessage(locale, tag string) string {
+	for _, l := range []string{locale, DefaultLocale} {
+		for _, t := range []string{tag, defaultMessage} {
+			if tmpl, ok := messages[l][t]; ok {
+				return tmpl
+			}
+		}
+	}
+	return ""
+}
+
+// tagMessage returns the template declared on the field of err within root:
+//
+//	Name  string `validate:"required,min=3" validate_msg:"required=Name is required,min=At least {param} letters"`
+//	Email string `validate:"required,email" msg:"{value} is not a valid email"`
+//
+// validate_msg is consulted by tag first, then msg applies to every tag of the field.
+func tagMessage(root reflect.Type, err validator.FieldError) (string, bool) {
+	if root == nil {
+		return "", false
+	}
+	owner, fld, ok := namespaceField(root, err.StructNamespace())
+	if !ok {
+		return "", false
+	}
+	key := fieldMessagesKey{owner: owner, field: fld.Name}
+	cached, ok := fieldMessages.Load(key)
+	if !ok {
+		cached, _ = fieldMessages.LoadOrStore(key, parseFieldMessage(fld))
+	}
+	fm := cached.(fieldMessage)
+	for _, tag := range []string{err.Tag(), err.ActualTag()} {
+		if tmpl, ok := fm.byTag[tag]; ok {
+			return tmpl, true
+		}
+	}
+	return fm.msg, fm.msg != ""
+}
+
+func parseFieldMessage(fld reflect.StructField) fieldMessage {
+	fm := fieldMessage{msg: fld.Tag.Get("msg"), byTag: make(map[string]string)}
+	tag := fld.Tag.Get("validate_msg")
+	matches := validateMsgRegex.FindAllStringSubmatchIndex(tag, -1)
+	for i, m := range matches {
+		end := len(tag)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		fm.byTag[tag[m[2]:m[3]]] = strings.TrimSpace(tag[m[1]:end])
+	}
+	return fm
+}
+
+// namespaceField finds the struct field of a namespace such as Order.Items[3].Labels[a.b].City
+// within root, along with the struct type declaring it.
+func namespaceField(root reflect.Type, namespace string) (reflect.Type, reflect.StructField, bool) {
+	i := strings.IndexByte(namespace, '.')
+	if i < 0 {
+		return nil, reflect.StructField{}, false
+	}
+	return walkNamespace(root, namespace[i:], nil, reflect.StructField{})
+}
+
+// walkNamespace resolves rest following a value of type t, fld is the last field passed
+// and owner the struct declaring it. Map keys may contain dots and brackets, the
+// closing bracket of a key is the one the types of the remaining namespace agree with.
+func walkNamespace(t reflect.Type, rest string, owner reflect.Type, fld reflect.StructField) (reflect.Type, reflect.StructField, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case rest == "":
+		return owner, fld, owner != nil
+	case rest[0] == '.' && t.Kind() == reflect.Struct:
+		rest = rest[1:]
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		f, ok := t.FieldByName(rest[:end])
+		if !ok {
+			return nil, reflect.StructField{}, false
+		}
+		return walkNamespace(f.Type, rest[end:], t, f)
+	case rest[0] == '[' && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map):
+		for j := 1; j < len(rest); j++ {
+			if rest[j] != ']' {
+				continue
+			}
+			if o, f, ok := walkNamespace(t.Elem(), rest[j+1:], owner, fld); ok {
+				return o, f, true
+			}
+		}
+	}
+	return nil, reflect.StructField{}, false
+}
+
+func renderMessage(tmpl string, ev ErrorValidator) string {
+	param := ev.Param
+	var format, example, min, max string
+	switch ev.Tag {
+	case "enum", "enum_strict":
+		param = strings.Join(enumValues(param), ", ")
+	case "oneof":
+		param = strings.Join(splitEnumParams(param), ", ")
+	case "date":
+		format, example = layoutMessage(paramOr(param, DateLayout))
+	case "datetime":
+		format, example = layoutMessage(paramOr(param, DatetimeLayout))
+	case "daterange":
+		bounds := strings.SplitN(paramOr(param, DefaultDateRange), "~", 2)
+		min, max = bounds[0], bounds[len(bounds)-1]
+	}
+	return strings.NewReplacer(
+		"{field}", ev.Field, "{param}", param, "{value}", ev.Value,
+		"{format}", format, "{example}", example, "{min}", min, "{max}", max,
+	).Replace(tmpl)
+}
+
+// layoutMessage describes a time layout, e.g. DD/MM/YYYY and 02/01/2006 for 02/01/2006.
+func layoutMessage(layout string) (format, example string) {
+	example = time.Date(2006, 1, 2, 15, 4, 5, 0, time.FixedZone("", 7*60*60)).Format(layout)
+	if layout == time.RFC3339 {
+		return "RFC 3339", example
+	}
+	return layoutNames.Replace(layout), example
+}
@@ -0,0 +1,148 @@
+package valkyrie
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type orderForm struct {
+	Name     string `json:"name" validate:"required"`
+	Date     string `json:"date" validate:"date"`
+	Birthday string `json:"birthday" validate:"daterange"`
+	Payment  string `json:"payment" validate:"enum=ovo gopay 'bank transfer'"`
+	Qty      int    `json:"qty" validate:"gte=1"`
+}
+
+func TestMatchLocale(t *testing.T) {
+	assert.Equal(t, LocaleID, MatchLocale("id"))
+	assert.Equal(t, LocaleID, MatchLocale("id-ID,id;q=0.9,en;q=0.8"))
+	assert.Equal(t, LocaleEN, MatchLocale("fr-FR, en-US;q=0.8, id;q=0.5"))
+	assert.Equal(t, LocaleID, MatchLocale("en;q=0.2, id;q=0.7"))
+	assert.Equal(t, DefaultLocale, MatchLocale("fr, de;q=0.5"))
+	assert.Equal(t, DefaultLocale, MatchLocale(""))
+
+	assert.Equal(t, DefaultLocale, LocaleFromContext(context.Background()))
+	assert.Equal(t, LocaleID, LocaleFromContext(WithLocale(context.Background(), "id-ID")))
+}
+
+func TestValidateMessages(t *testing.T) {
+	form := orderForm{Date: "01/02/2020", Birthday: "1800-01-01", Payment: "cash"}
+	errs := Validate(form)
+	assert.Equal(t, []string{
+		"name is required",
+		"date must be a date formatted as YYYY-MM-DD, e.g. 2006-01-02",
+		"birthday must be a date between 1900-01-01 and 2100-01-01",
+		"payment must be one of: ovo, gopay, bank transfer",
+		"qty must be greater than or equal to 1",
+	}, errorMessages(errs))
+
+	errs = Translate(WithLocale(context.Background(), "id-ID,id;q=0.9"), errs)
+	assert.Equal(t, []string{
+		"name wajib diisi",
+		"date harus berupa tanggal dengan format YYYY-MM-DD, contoh 2006-01-02",
+		"birthday harus berupa tanggal antara 1900-01-01 dan 2100-01-01",
+		"payment harus salah satu dari: ovo, gopay, bank transfer",
+		"qty harus lebih besar dari atau sama dengan 1",
+	}, errorMessages(errs))
+
+	errs = NewValidator(WithDefaultLocale(LocaleID)).Validate(orderForm{Date: "2020-01-02", Birthday: "2000-01-01", Payment: "ovo", Qty: 1})
+	assert.Equal(t, []string{"name wajib diisi"}, errorMessages(errs))
+}
+
+func TestRegisterMessages(t *testing.T) {
+	RegisterMessages("jv", map[string]string{"required": "{field} kudu diisi"})
+	defer func() {
+		messagesMtx.Lock()
+		delete(messages, "jv")
+		messagesMtx.Unlock()
+	}()
+
+	ctx := WithLocale(context.Background(), "jv-ID")
+	errs := Translate(ctx, Validate(orderForm{Date: "x", Birthday: "2000-01-01", Payment: "ovo", Qty: 1}))
+	// tags missing from the locale fall back to DefaultLocale
+	assert.Equal(t, []string{
+		"name kudu diisi",
+		"date must be a date formatted as YYYY-MM-DD, e.g. 2006-01-02",
+	}, errorMessages(errs))
+
+	// tags without a template use the default one
+	assert.Equal(t, "Invalid Type x for input code", errorMessage(LocaleEN, ErrorValidator{Tag: "custom", Field: "code", Value: "x"}))
+	assert.Equal(t, "code tidak valid", errorMessage(LocaleID, ErrorValidator{Tag: "custom", Field: "code", Value: "x"}))
+}
+
+func errorMessages(errs []ErrorValidator) []string {
+	msgs := make([]string, 0, len(errs))
+	for _, ev := range errs {
+		msgs = append(msgs, ev.Message)
+	}
+	return msgs
+}
+
+type (
+	contactForm struct {
+		Name    string           `json:"name" validate:"required,min=3" validate_msg:"required=Name is required, please,min=Name needs {param} letters, got {value}"`
+		Email   string           `json:"email" validate:"required,email" msg:"{field}: {value} is not an email"`
+		Phone   string           `json:"phone" validate:"required"`
+		Address *contactAddress  `json:"address"`
+		Others  []contactAddress `json:"others" validate:"dive"`
+	}
+	contactAddress struct {
+		City string `json:"city" validate:"required" msg:"City is required"`
+	}
+)
+
+func TestValidateTagMessages(t *testing.T) {
+	errs := Validate(contactForm{
+		Name:    "ab",
+		Email:   "nope",
+		Address: &contactAddress{},
+		Others:  []contactAddress{{City: "Bandung"}, {}},
+	})
+	assert.Equal(t, []string{
+		"Name needs 3 letters, got ab",
+		"email: nope is not an email",
+		"phone is required",
+		"City is required",
+		"City is required",
+	}, errorMessages(errs))
+	assert.Equal(t, "others[1].city", errs[4].Path)
+
+	errs = Validate(contactForm{Email: "a@b.co", Phone: "1"})
+	assert.Equal(t, []string{"Name is required, please"}, errorMessages(errs))
+
+	// tag messages are not translated, other messages are
+	errs = ValidateCtx(WithLocale(context.Background(), "id"), contactForm{})
+	assert.Equal(t, []string{"Name is required, please", "email:  is not an email", "phone wajib diisi"}, errorMessages(errs))
+}
+
+func TestValidateTagMessagesKeys(t *testing.T) {
+	type labelForm struct {
+		Labels  map[string]contactAddress `json:"labels" validate:"dive"`
+		Aliases map[string]string         `json:"aliases" validate:"dive,required" msg:"{field} needs a value"`
+	}
+	form := labelForm{
+		Labels:  map[string]contactAddress{"a.b": {}, "x].City[y": {}},
+		Aliases: map[string]string{"c.d[0]": ""},
+	}
+	errs := Validate(form)
+	assert.Len(t, errs, 3)
+	for _, ev := range errs {
+		assert.Contains(t, []string{"City is required", "aliases[c.d[0]] needs a value"}, ev.Message)
+	}
+
+	// indexes and keys of the values share the entries of their fields
+	others := make([]contactAddress, 100)
+	Validate(contactForm{Name: "abc", Email: "a@b.co", Phone: "1", Others: others})
+	Validate(labelForm{Labels: map[string]contactAddress{"new": {}}, Aliases: map[string]string{"new": ""}})
+	entries := 0
+	fieldMessages.Range(func(key, _ interface{}) bool {
+		if k := key.(fieldMessagesKey); k.owner == reflect.TypeOf(contactAddress{}) {
+			entries++
+		}
+		return true
+	})
+	assert.Equal(t, 1, entries)
+}
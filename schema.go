@@ -0,0 +1,240 @@
+package valkyrie
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/rs/zerolog"
+)
+
+// JSONSchemaDraft is the JSON Schema dialect emitted by ConfigSchema.
+const JSONSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// jsonSchema is the subset of JSON Schema ConfigSchema emits.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Type                 interface{}            `json:"type,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64               `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+	// Env lists the environment variables overriding the key.
+	Env []string `json:"x-env,omitempty"`
+	// EnvRequired marks a key whose env-required tag makes one of Env mandatory.
+	EnvRequired bool `json:"x-env-required,omitempty"`
+}
+
+// ConfigSchema emits a JSON Schema describing the config files read into cfg by Config.
+// Keys follow the yaml tags, env-default becomes default, env-description becomes
+// description, and the validate tags required, enum, oneof, min, max, len, gt, gte,
+// lt, lte, email, url, date and datetime become the matching schema keywords. Keys
+// bound to env tags or with an env-default are never required in the files,
+// env-required becomes x-env-required.
+func ConfigSchema(cfg interface{}) ([]byte, error) {
+	s := schemaOf(reflect.TypeOf(cfg), "")
+	s.Schema = JSONSchemaDraft
+	return json.MarshalIndent(s, "", "  ")
+}
+
+func schemaOf(t reflect.Type, envPrefix string) *jsonSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	s := &jsonSchema{}
+	switch t {
+	case durationType:
+		s.Type, s.Format = []string{"string", "number"}, "duration"
+		return s
+	case reflect.TypeOf(ByteSize(0)):
+		s.Type = []string{"string", "integer"}
+		return s
+	case reflect.TypeOf(URL("")):
+		s.Type, s.Format = "string", "uri"
+		return s
+	case reflect.TypeOf(LogLevel(0)):
+		s.Type = "string"
+		for _, lvl := range []zerolog.Level{zerolog.TraceLevel, zerolog.DebugLevel, zerolog.InfoLevel,
+			zerolog.WarnLevel, zerolog.ErrorLevel, zerolog.FatalLevel, zerolog.PanicLevel} {
+			s.Enum = append(s.Enum, lvl.String())
+		}
+		return s
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if t == reflect.TypeOf(time.Time{}) {
+			s.Type, s.Format = "string", "date-time"
+			break
+		}
+		s.Type = "object"
+		s.Properties = make(map[string]*jsonSchema)
+		for i := 0; i < t.NumField(); i++ {
+			fld := t.Field(i)
+			name := configKey(fld)
+			if fld.PkgPath != "" || name == "-" {
+				continue
+			}
+			prop := schemaOf(fld.Type, envPrefix+fld.Tag.Get(cleanenv.TagEnvPrefix))
+			if !isConfigStruct(fld.Type) {
+				if envs := fld.Tag.Get(cleanenv.TagEnv); envs != "" {
+					for _, env := range strings.Split(envs, cleanenv.DefaultSeparator) {
+						prop.Env = append(prop.Env, envPrefix+env)
+					}
+				}
+				if def, ok := fld.Tag.Lookup(cleanenv.TagEnvDefault); ok {
+					prop.Default = schemaValue(fld.Type, def)
+				}
+				_, prop.EnvRequired = fld.Tag.Lookup(cleanenv.TagEnvRequired)
+			}
+			prop.Description = fld.Tag.Get(cleanenv.TagEnvDescription)
+			// keys given by the environment or a default may be left out of the files
+			_, hasDefault := fld.Tag.Lookup(cleanenv.TagEnvDefault)
+			if prop.applyRules(fld.Type, fld.Tag.Get("validate")) && len(prop.Env) == 0 && !hasDefault {
+				s.Required = append(s.Required, name)
+			}
+			s.Properties[name] = prop
+		}
+	case reflect.String:
+		s.Type = "string"
+	case reflect.Bool:
+		s.Type = "boolean"
+	case reflect.Int64:
+		if t == reflect.TypeOf(time.Duration(0)) {
+			s.Type, s.Format = "string", "duration"
+			break
+		}
+		s.Type = "integer"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.Type = "integer"
+	case reflect.Float32, reflect.Float64:
+		s.Type = "number"
+	case reflect.Slice, reflect.Array:
+		s.Type = "array"
+		s.Items = schemaOf(t.Elem(), envPrefix)
+	case reflect.Map:
+		s.Type = "object"
+		s.AdditionalProperties = schemaOf(t.Elem(), envPrefix)
+	}
+	return s
+}
+
+// applyRules translates the validate tag of a field of type t,
+// it reports whether the field is required.
+func (s *jsonSchema) applyRules(t reflect.Type, tag string) (required bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		name, param := rule, ""
+		if j := strings.Index(rule, "="); j >= 0 {
+			name, param = rule[:j], rule[j+1:]
+		}
+		switch name {
+		case "dive":
+			if s.Items != nil {
+				s.Items.applyRules(t.Elem(), strings.Join(rules[i+1:], ","))
+			}
+			return required
+		case "required":
+			required = true
+		case "enum", "enum_strict":
+			// enum checks every element of a slice
+			target, elem := s, t
+			if s.Items != nil {
+				target, elem = s.Items, t.Elem()
+			}
+			for _, v := range enumValues(param) {
+				target.Enum = append(target.Enum, schemaValue(elem, v))
+			}
+		case "oneof":
+			for _, v := range splitEnumParams(param) {
+				s.Enum = append(s.Enum, schemaValue(t, v))
+			}
+		case "email":
+			s.Format = "email"
+		case "url", "uri":
+			s.Format = "uri"
+		case "date":
+			// custom layouts are not the date format of JSON Schema
+			if paramOr(param, DateLayout) == DateLayout {
+				s.Format = "date"
+			}
+		case "datetime":
+			if paramOr(param, DatetimeLayout) == DatetimeLayout {
+				s.Format = "date-time"
+			}
+		case "min", "gte":
+			s.bound(param, &s.Minimum, &s.MinLength, &s.MinItems)
+		case "max", "lte":
+			s.bound(param, &s.Maximum, &s.MaxLength, &s.MaxItems)
+		case "len":
+			s.bound(param, &s.Minimum, &s.MinLength, &s.MinItems)
+			s.bound(param, &s.Maximum, &s.MaxLength, &s.MaxItems)
+			s.Minimum, s.Maximum = nil, nil
+		case "gt":
+			s.bound(param, &s.ExclusiveMinimum, nil, nil)
+		case "lt":
+			s.bound(param, &s.ExclusiveMaximum, nil, nil)
+		}
+	}
+	return required
+}
+
+// bound sets a numeric limit or, for strings and arrays, a length limit.
+func (s *jsonSchema) bound(param string, num **float64, length, items **int) {
+	switch s.Type {
+	case "integer", "number":
+		if f, err := strconv.ParseFloat(param, 64); err == nil {
+			*num = &f
+		}
+	case "string":
+		if n, err := strconv.Atoi(param); err == nil && length != nil {
+			*length = &n
+		}
+	case "array":
+		if n, err := strconv.Atoi(param); err == nil && items != nil {
+			*items = &n
+		}
+	}
+}
+
+// schemaValue converts the raw tag value v to the JSON type of t.
+func schemaValue(t reflect.Type, v string) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Duration(0)) || t == reflect.TypeOf(Duration(0)) || t == reflect.TypeOf(ByteSize(0)) {
+		return v
+	}
+	val := reflect.New(t).Elem()
+	if err := setConfigValue(val, v); err != nil {
+		return v
+	}
+	return val.Interface()
+}
+
+// splitEnumParams splits space separated values, 'quoted values' may contain spaces.
+func splitEnumParams(param string) []string {
+	var values []string
+	for _, p := range enumParamsRegex.FindAllString(param, -1) {
+		values = append(values, strings.Trim(p, "'[]"))
+	}
+	return values
+}
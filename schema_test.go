@@ -0,0 +1,102 @@
+package valkyrie
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type schemaConfig struct {
+	App struct {
+		Name     string         `yaml:"name" validate:"required,min=3"`
+		Port     int            `yaml:"port" env:"APP_PORT" env-default:"8778" validate:"gt=1024,lte=65535"`
+		Env      string         `yaml:"env" env-default:"development" validate:"enum=development staging production"`
+		Debug    bool           `yaml:"debug" env-description:"verbose logging"`
+		ExpireIn *time.Duration `yaml:"expire_in" env-default:"40s"`
+		Hosts    []string       `yaml:"hosts" validate:"min=1,dive,url"`
+		Host     string         `yaml:"host" env:"APP_HOST" validate:"required"`
+		Zone     string         `yaml:"zone" env-default:"Asia/Jakarta" validate:"required"`
+	} `yaml:"App"`
+	DB struct {
+		DsnMain string `yaml:"dsn_main" env:"DSN_MAIN" env-required:"true"`
+	} `yaml:"db" env-prefix:"APP_"`
+}
+
+func TestConfigSchema(t *testing.T) {
+	b, err := ConfigSchema(&schemaConfig{})
+	assert.NoError(t, err)
+
+	var schema map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &schema))
+	assert.Equal(t, JSONSchemaDraft, schema["$schema"])
+
+	want := `{
+  "type": "object",
+  "properties": {
+    "debug": {
+      "type": "boolean",
+      "description": "verbose logging"
+    },
+    "env": {
+      "type": "string",
+      "default": "development",
+      "enum": [
+        "development",
+        "staging",
+        "production"
+      ]
+    },
+    "expire_in": {
+      "type": "string",
+      "format": "duration",
+      "default": "40s"
+    },
+    "host": {
+      "type": "string",
+      "x-env": [
+        "APP_HOST"
+      ]
+    },
+    "zone": {
+      "type": "string",
+      "default": "Asia/Jakarta"
+    },
+    "hosts": {
+      "type": "array",
+      "minItems": 1,
+      "items": {
+        "type": "string",
+        "format": "uri"
+      }
+    },
+    "name": {
+      "type": "string",
+      "minLength": 3
+    },
+    "port": {
+      "type": "integer",
+      "default": 8778,
+      "exclusiveMinimum": 1024,
+      "maximum": 65535,
+      "x-env": [
+        "APP_PORT"
+      ]
+    }
+  },
+  "required": [
+    "name"
+  ]
+}`
+	app, err := json.MarshalIndent(schema["properties"].(map[string]interface{})["App"], "", "  ")
+	assert.NoError(t, err)
+	assert.JSONEq(t, want, string(app))
+
+	db := schema["properties"].(map[string]interface{})["db"].(map[string]interface{})
+	// the environment supplies keys left out of the files
+	assert.Nil(t, db["required"])
+	dsn := db["properties"].(map[string]interface{})["dsn_main"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"APP_DSN_MAIN"}, dsn["x-env"])
+	assert.Equal(t, true, dsn["x-env-required"])
+}
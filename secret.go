@@ -0,0 +1,238 @@
+package valkyrie
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecretNotFound is returned when a secret reference cannot be resolved.
+var SecretNotFound = errors.New("secret not found")
+
+type (
+	// SecretResolver resolves the part after "<scheme>:" of a config value
+	// into the actual secret, e.g. "db/password" of "vault:db/password".
+	SecretResolver interface {
+		Resolve(ref string) (string, error)
+	}
+
+	// KeySecretResolver is a SecretResolver that is also given the dot
+	// separated key path of the value, e.g. for values bound to their key.
+	KeySecretResolver interface {
+		SecretResolver
+		ResolveKey(key, ref string) (string, error)
+	}
+
+	// SecretResolverFunc adapts a function to the SecretResolver interface.
+	SecretResolverFunc func(ref string) (string, error)
+
+	// FileSecretStore is a local stand-in for vault-like stores,
+	// it resolves references from a flat YAML map of key to secret.
+	FileSecretStore struct {
+		Path string
+
+		once    sync.Once
+		secrets map[string]string
+		err     error
+	}
+)
+
+// Resolve implements SecretResolver.
+func (f SecretResolverFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+// Resolve implements SecretResolver, the file is read on first use.
+func (s *FileSecretStore) Resolve(ref string) (string, error) {
+	s.once.Do(func() {
+		var b []byte
+		if b, s.err = os.ReadFile(s.Path); s.err == nil {
+			s.err = yaml.Unmarshal(b, &s.secrets)
+		}
+	})
+	if s.err != nil {
+		return "", s.err
+	}
+	v, ok := s.secrets[ref]
+	if !ok {
+		return "", fmt.Errorf("%w: %s in %s", SecretNotFound, ref, s.Path)
+	}
+	return v, nil
+}
+
+// DefaultSecretResolvers returns the built-in schemes:
+//
+//	env:NAME                  value of the environment variable NAME
+//	file:/run/secrets/name    content of the file, trailing newlines trimmed
+//	base64:c2VrcmV0           standard base64 decoded value
+//	enc:v1:..., enc:v2:...    decrypted with the key from ConfigCipherFromEnv, read on first use
+func DefaultSecretResolvers() map[string]SecretResolver {
+	return map[string]SecretResolver{
+		"env": SecretResolverFunc(func(ref string) (string, error) {
+			v, ok := os.LookupEnv(ref)
+			if !ok {
+				return "", fmt.Errorf("%w: environment variable %s", SecretNotFound, ref)
+			}
+			return v, nil
+		}),
+		"file": SecretResolverFunc(func(ref string) (string, error) {
+			b, err := os.ReadFile(ref)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimRight(string(b), "\r\n"), nil
+		}),
+		"base64": SecretResolverFunc(func(ref string) (string, error) {
+			b, err := Base64Decode(ref)
+			return string(b), err
+		}),
+		"enc": &envCipher{},
+	}
+}
+
+// envCipher resolves the "enc" scheme with the cipher of ConfigCipherFromEnv,
+// it is created once for all the values.
+type envCipher struct {
+	once   sync.Once
+	cipher *ConfigCipher
+	err    error
+}
+
+func (e *envCipher) load() (*ConfigCipher, error) {
+	e.once.Do(func() {
+		e.cipher, e.err = ConfigCipherFromEnv()
+	})
+	return e.cipher, e.err
+}
+
+// Resolve implements SecretResolver.
+func (e *envCipher) Resolve(ref string) (string, error) {
+	c, err := e.load()
+	if err != nil {
+		return "", err
+	}
+	return c.Resolve(ref)
+}
+
+// ResolveKey implements KeySecretResolver.
+func (e *envCipher) ResolveKey(key, ref string) (string, error) {
+	c, err := e.load()
+	if err != nil {
+		return "", err
+	}
+	return c.ResolveKey(key, ref)
+}
+
+// ResolveSecrets replaces every string in cfg of the form "<scheme>:<ref>"
+// whose scheme has a resolver, extra resolvers take precedence over the defaults.
+// A leading backslash keeps a value as it is, \file:test.db becomes file:test.db.
+func ResolveSecrets(cfg interface{}, extra map[string]SecretResolver) error {
+	resolvers := DefaultSecretResolvers()
+	for scheme, r := range extra {
+		resolvers[scheme] = r
+	}
+	return walkConfigStrings(reflect.ValueOf(cfg), "", func(path, s string) (string, error) {
+		escaped := strings.HasPrefix(s, `\`)
+		i := strings.Index(s, ":")
+		if i <= 0 {
+			return s, nil
+		}
+		scheme := s[:i]
+		if escaped {
+			scheme = scheme[1:]
+		}
+		r, ok := resolvers[scheme]
+		if !ok {
+			return s, nil
+		}
+		if escaped {
+			return s[1:], nil
+		}
+		var v string
+		var err error
+		if kr, ok := r.(KeySecretResolver); ok {
+			v, err = kr.ResolveKey(path, s[i+1:])
+		} else {
+			v, err = r.Resolve(s[i+1:])
+		}
+		if err != nil {
+			return s, fmt.Errorf("config %s: %w", path, err)
+		}
+		return v, nil
+	})
+}
+
+// walkConfigStrings calls fn for every settable string reachable from v,
+// path is the dot separated key path of the value, and replaces it with the result.
+func walkConfigStrings(v reflect.Value, path string, fn func(path, s string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Interface {
+			// values held by an interface are not addressable, replace them as a whole
+			// keeping their named string type
+			if e := v.Elem(); e.Kind() == reflect.String && v.CanSet() {
+				if !e.Type().AssignableTo(v.Type()) {
+					return nil
+				}
+				s, err := fn(path, e.String())
+				if err != nil {
+					return err
+				}
+				v.Set(reflect.ValueOf(s).Convert(e.Type()))
+				return nil
+			}
+		}
+		return walkConfigStrings(v.Elem(), path, fn)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			fld := v.Type().Field(i)
+			if fld.PkgPath != "" {
+				continue
+			}
+			if err := walkConfigStrings(v.Field(i), joinKey(path, configKey(fld)), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkConfigStrings(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			key := joinKey(path, fmt.Sprintf("%v", k.Interface()))
+			e := reflect.New(v.Type().Elem()).Elem()
+			e.Set(v.MapIndex(k))
+			if err := walkConfigStrings(e, key, fn); err != nil {
+				return err
+			}
+			v.SetMapIndex(k, e)
+		}
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		s, err := fn(path, v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	}
+	return nil
+}
+
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
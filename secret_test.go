@@ -0,0 +1,128 @@
+package valkyrie
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type secretConfig struct {
+	App struct {
+		SecretKey string            `yaml:"secret_key"`
+		Token     string            `yaml:"token"`
+		Password  string            `yaml:"password"`
+		Vault     string            `yaml:"vault"`
+		Hosts     []string          `yaml:"hosts"`
+		Labels    map[string]string `yaml:"labels"`
+	} `yaml:"App"`
+}
+
+func TestResolveSecrets(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "password", "root123\n")
+	writeConfig(t, dir, "vault.yaml", "db/password: from-vault\n")
+	t.Setenv("APP_SECRET_KEY", "sekret")
+
+	var cfg secretConfig
+	cfg.App.SecretKey = "env:APP_SECRET_KEY"
+	cfg.App.Token = "base64:" + Base64Encode([]byte("t0ken"))
+	cfg.App.Password = "file:" + filepath.Join(dir, "password")
+	cfg.App.Vault = "vault:db/password"
+	cfg.App.Hosts = []string{"http://localhost", "env:APP_SECRET_KEY"}
+	cfg.App.Labels = map[string]string{"key": "env:APP_SECRET_KEY"}
+
+	err := ResolveSecrets(&cfg, map[string]SecretResolver{
+		"vault": &FileSecretStore{Path: filepath.Join(dir, "vault.yaml")},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "sekret", cfg.App.SecretKey)
+	assert.Equal(t, "t0ken", cfg.App.Token)
+	assert.Equal(t, "root123", cfg.App.Password)
+	assert.Equal(t, "from-vault", cfg.App.Vault)
+	assert.Equal(t, []string{"http://localhost", "sekret"}, cfg.App.Hosts)
+	assert.Equal(t, map[string]string{"key": "sekret"}, cfg.App.Labels)
+}
+
+type secretRef string
+
+func (s secretRef) String() string { return string(s) }
+
+func TestResolveSecretsInterface(t *testing.T) {
+	t.Setenv("APP_SECRET_KEY", "sekret")
+	cfg := struct {
+		Ref   fmt.Stringer
+		Any   interface{}
+		Extra map[string]interface{}
+	}{
+		Ref:   secretRef("env:APP_SECRET_KEY"),
+		Any:   secretRef("env:APP_SECRET_KEY"),
+		Extra: map[string]interface{}{"key": "env:APP_SECRET_KEY", "port": 80},
+	}
+
+	assert.NoError(t, ResolveSecrets(&cfg, nil))
+	assert.Equal(t, secretRef("sekret"), cfg.Ref)
+	assert.Equal(t, secretRef("sekret"), cfg.Any)
+	assert.Equal(t, map[string]interface{}{"key": "sekret", "port": 80}, cfg.Extra)
+}
+
+func TestResolveSecretsFail(t *testing.T) {
+	var cfg secretConfig
+	cfg.App.SecretKey = "env:VALKYRIE_MISSING_SECRET"
+
+	err := ResolveSecrets(&cfg, nil)
+
+	assert.True(t, errors.Is(err, SecretNotFound))
+	assert.Contains(t, err.Error(), "App.secret_key")
+}
+
+func TestConfigSecrets(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "app.yaml", "App:\n  secret_key: env:APP_SECRET_KEY\n  vault: vault:db/password\n")
+	writeConfig(t, dir, "vault.yaml", "db/password: from-vault\n")
+	t.Setenv("APP_SECRET_KEY", "sekret")
+
+	var cfg secretConfig
+	err := Config(ConfigOpts{
+		Config:    &cfg,
+		Filenames: []string{"app.yaml"},
+		Paths:     []string{dir},
+		Secrets: map[string]SecretResolver{
+			"vault": &FileSecretStore{Path: filepath.Join(dir, "vault.yaml")},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "sekret", cfg.App.SecretKey)
+	assert.Equal(t, "from-vault", cfg.App.Vault)
+}
+
+func TestConfigSecretsOptIn(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "app.yaml", "App:\n  secret_key: env:APP_SECRET_KEY\n  token: file:test.db\n  password: \\file:test.db\n")
+	t.Setenv("APP_SECRET_KEY", "sekret")
+
+	// values are kept as they are unless secrets are resolved
+	var cfg secretConfig
+	err := Config(ConfigOpts{Config: &cfg, Filenames: []string{"app.yaml"}, Paths: []string{dir}})
+	assert.NoError(t, err)
+	assert.Equal(t, "env:APP_SECRET_KEY", cfg.App.SecretKey)
+	assert.Equal(t, "file:test.db", cfg.App.Token)
+	assert.Equal(t, `\file:test.db`, cfg.App.Password)
+
+	cfg = secretConfig{}
+	err = Config(ConfigOpts{Config: &cfg, Filenames: []string{"app.yaml"}, Paths: []string{dir}, ResolveSecrets: true})
+	assert.Error(t, err)
+
+	// a leading backslash escapes a reference
+	writeConfig(t, dir, "app.yaml", "App:\n  secret_key: env:APP_SECRET_KEY\n  token: \\file:test.db\n  vault: \\unknown:x\n")
+	cfg = secretConfig{}
+	err = Config(ConfigOpts{Config: &cfg, Filenames: []string{"app.yaml"}, Paths: []string{dir}, ResolveSecrets: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "sekret", cfg.App.SecretKey)
+	assert.Equal(t, "file:test.db", cfg.App.Token)
+	assert.Equal(t, `\unknown:x`, cfg.App.Vault)
+}
@@ -0,0 +1,372 @@
+package valkyrie
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Source provides a layer of config values as a nested map keyed like the
+	// config file, e.g. {"App": {"port": 9000}}. Keys are matched against the
+	// config struct case-insensitively and unknown keys are ignored.
+	Source interface {
+		// Name identifies the source in reports, e.g. a file path or URL.
+		Name() string
+		Load() (map[string]interface{}, error)
+	}
+
+	// FileSource loads a yaml, json or toml file.
+	FileSource struct {
+		Path string
+	}
+
+	// EnvSource loads environment variables starting with Prefix,
+	// Separator (default "__") splits the rest into keys:
+	// APP_APP__PORT=9000 with Prefix "APP_" sets App.port.
+	EnvSource struct {
+		Prefix    string
+		Separator string
+	}
+
+	// MapSource loads in-memory values, keys may be nested maps or dot separated paths.
+	MapSource struct {
+		Label  string
+		Values map[string]interface{}
+	}
+
+	// HTTPSource loads a JSON or YAML document from an HTTP endpoint,
+	// the format follows the Content-Type of the response.
+	HTTPSource struct {
+		URL    string
+		Header http.Header
+		// Client defaults to an http.Client with a 10 second timeout.
+		Client *http.Client
+	}
+
+	// KVStore is a key-value store holding config values,
+	// List returns every key starting with prefix.
+	KVStore interface {
+		List(prefix string) (map[string]string, error)
+	}
+
+	// KVSource loads the keys below Prefix from Store,
+	// "/" separates the keys: <prefix>App/port sets App.port.
+	KVSource struct {
+		Store  KVStore
+		Prefix string
+	}
+
+	// MemoryKV is an in-memory KVStore, e.g. a stand-in for consul or etcd in tests.
+	MemoryKV struct {
+		mtx  sync.RWMutex
+		data map[string]string
+	}
+)
+
+func (s FileSource) Name() string { return s.Path }
+
+func (s FileSource) Load() (map[string]interface{}, error) {
+	decode, _, err := readConfigFile(s.Path, os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]interface{}
+	if err = decode(&tree); err != nil {
+		return nil, fmt.Errorf("config file %s parsing error: %w", s.Path, err)
+	}
+	return tree, nil
+}
+
+func (s EnvSource) Name() string { return "env " + s.Prefix + "*" }
+
+func (s EnvSource) Load() (map[string]interface{}, error) {
+	sep := s.Separator
+	if sep == "" {
+		sep = "__"
+	}
+	values := make(map[string]interface{})
+	for _, kv := range os.Environ() {
+		i := strings.Index(kv, "=")
+		if i <= 0 || !strings.HasPrefix(kv[:i], s.Prefix) || i == len(s.Prefix) {
+			continue
+		}
+		key := strings.ReplaceAll(kv[len(s.Prefix):i], sep, ".")
+		values[key] = kv[i+1:]
+	}
+	return expandKeys(values), nil
+}
+
+func (s MapSource) Name() string {
+	if s.Label == "" {
+		return "map"
+	}
+	return s.Label
+}
+
+func (s MapSource) Load() (map[string]interface{}, error) {
+	return expandKeys(s.Values), nil
+}
+
+func (s HTTPSource) Name() string { return s.URL }
+
+func (s HTTPSource) Load() (map[string]interface{}, error) {
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range s.Header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("config source %s: %s", s.URL, resp.Status)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	ext := ".json"
+	if strings.Contains(resp.Header.Get("Content-Type"), "yaml") {
+		ext = ".yaml"
+	}
+	var tree map[string]interface{}
+	if err = decodeConfig(bytes.NewReader(b), ext, &tree); err != nil {
+		return nil, fmt.Errorf("config source %s parsing error: %w", s.URL, err)
+	}
+	return tree, nil
+}
+
+func (s KVSource) Name() string { return "kv " + s.Prefix }
+
+func (s KVSource) Load() (map[string]interface{}, error) {
+	pairs, err := s.Store.List(s.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{}, len(pairs))
+	for k, v := range pairs {
+		key := strings.Trim(strings.TrimPrefix(k, s.Prefix), "/")
+		if key != "" {
+			values[strings.ReplaceAll(key, "/", ".")] = v
+		}
+	}
+	return expandKeys(values), nil
+}
+
+// NewMemoryKV creates a MemoryKV holding data.
+func NewMemoryKV(data map[string]string) *MemoryKV {
+	kv := &MemoryKV{data: make(map[string]string, len(data))}
+	for k, v := range data {
+		kv.data[k] = v
+	}
+	return kv
+}
+
+func (m *MemoryKV) Put(key, value string) {
+	m.mtx.Lock()
+	if m.data == nil {
+		m.data = make(map[string]string)
+	}
+	m.data[key] = value
+	m.mtx.Unlock()
+}
+
+func (m *MemoryKV) Delete(key string) {
+	m.mtx.Lock()
+	delete(m.data, key)
+	m.mtx.Unlock()
+}
+
+func (m *MemoryKV) List(prefix string) (map[string]string, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	out := make(map[string]string)
+	for k, v := range m.data {
+		if strings.HasPrefix(k, prefix) {
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+// expandKeys nests dot separated keys, {"App.port": 1} becomes {"App": {"port": 1}}.
+func expandKeys(values map[string]interface{}) map[string]interface{} {
+	tree := make(map[string]interface{})
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	// shorter paths first, so nested keys are merged into their parents
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := values[k]
+		if sub, ok := v.(map[string]interface{}); ok {
+			v = expandKeys(sub)
+		}
+		parts := strings.Split(k, ".")
+		node := tree
+		for _, p := range parts[:len(parts)-1] {
+			next, ok := node[p].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				node[p] = next
+			}
+			node = next
+		}
+		node[parts[len(parts)-1]] = v
+	}
+	return tree
+}
+
+// applyTree sets the values of tree on the struct v points to,
+// set is called with the key path of every value applied.
+func applyTree(v reflect.Value, tree map[string]interface{}, prefix string, set func(key string)) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("wrong type %v", v.Kind())
+	}
+	for k, val := range tree {
+		fv, name, ok := structField(v, k)
+		if !ok {
+			continue
+		}
+		key := joinKey(prefix, name)
+		if sub, ok := val.(map[string]interface{}); ok && isConfigStruct(fv.Type()) {
+			if err := applyTree(fv, sub, key, set); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := setConfigValue(fv, val); err != nil {
+			return fmt.Errorf("config %s: %w", key, err)
+		}
+		set(key)
+	}
+	return nil
+}
+
+// applyDurationTree sets the Duration fields of v holding a plain number in tree to that many units.
+func applyDurationTree(v reflect.Value, tree map[string]interface{}, prefix string, unit time.Duration) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	for k, val := range tree {
+		fv, name, ok := structField(v, k)
+		if !ok {
+			continue
+		}
+		key := joinKey(prefix, name)
+		if sub, ok := val.(map[string]interface{}); ok && isConfigStruct(fv.Type()) {
+			if err := applyDurationTree(fv, sub, key, unit); err != nil {
+				return err
+			}
+			continue
+		}
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		f, ok := plainNumber(val)
+		if fv.Type() != durationType || !ok {
+			continue
+		}
+		d, err := durationOf(f, unit)
+		if err != nil {
+			return fmt.Errorf("config %s: duration %v: %w", key, val, err)
+		}
+		fv.Set(reflect.ValueOf(d))
+	}
+	return nil
+}
+
+// plainNumber returns the number held by a decoded value or a string.
+func plainNumber(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// structField finds the field of v decoded from key, ignoring case.
+func structField(v reflect.Value, key string) (reflect.Value, string, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		fld := v.Type().Field(i)
+		if fld.PkgPath != "" {
+			continue
+		}
+		if name := configKey(fld); name != "-" && strings.EqualFold(name, key) {
+			return v.Field(i), name, true
+		}
+	}
+	return reflect.Value{}, "", false
+}
+
+func isConfigStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{})
+}
+
+// setConfigValue decodes val into fv, raw strings are parsed as yaml scalars
+// unless the field itself is a string or a cleanenv.Setter, slices also accept "a,b" lists.
+func setConfigValue(fv reflect.Value, val interface{}) error {
+	if s, ok := val.(string); ok {
+		if setter, ok := fv.Addr().Interface().(cleanenv.Setter); ok {
+			return setter.SetValue(s)
+		}
+		switch {
+		case fv.Kind() == reflect.String:
+			fv.SetString(s)
+			return nil
+		case fv.Kind() == reflect.Slice && !strings.HasPrefix(strings.TrimSpace(s), "["):
+			s = "[" + s + "]"
+		}
+		return yaml.Unmarshal([]byte(s), fv.Addr().Interface())
+	}
+	b, err := yaml.Marshal(val)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(b, fv.Addr().Interface())
+}
@@ -0,0 +1,98 @@
+package valkyrie
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sourceConfig struct {
+	App struct {
+		Name         string   `yaml:"name"`
+		Port         int      `yaml:"port"`
+		ReadTimeout  int      `yaml:"read_timeout"`
+		Debug        bool     `yaml:"debug"`
+		Hosts        []string `yaml:"hosts"`
+		WriteTimeout *int     `yaml:"write_timeout"`
+	} `yaml:"App"`
+}
+
+func TestConfigSources(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "app.yaml", "App:\n  name: laugh-tale\n  port: 8778\n  read_timeout: 5\n")
+	override := writeConfig(t, dir, "override.json", `{"App": {"hosts": ["a", "b"]}}`)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "sekret", r.Header.Get("X-Token"))
+		w.Header().Set("Content-Type", "application/x-yaml")
+		_, _ = w.Write([]byte("App:\n  port: 9000\n  debug: true\n"))
+	}))
+	defer srv.Close()
+
+	kv := NewMemoryKV(map[string]string{
+		"config/laugh-tale/App/port":          "9100",
+		"config/laugh-tale/App/write_timeout": "15",
+		"config/other/App/port":               "1",
+	})
+	t.Setenv("LT_APP__READ_TIMEOUT", "30")
+
+	var cfg sourceConfig
+	report, err := LoadConfig(ConfigOpts{
+		Config:    &cfg,
+		Filenames: []string{"app.yaml"},
+		Paths:     []string{dir},
+		Sources: []Source{
+			FileSource{Path: override},
+			HTTPSource{URL: srv.URL, Header: http.Header{"X-Token": {"sekret"}}},
+			KVSource{Store: kv, Prefix: "config/laugh-tale/"},
+			EnvSource{Prefix: "LT_"},
+			MapSource{Values: map[string]interface{}{"App.name": "from-map"}},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "from-map", cfg.App.Name)
+	assert.Equal(t, 9100, cfg.App.Port)
+	assert.Equal(t, 30, cfg.App.ReadTimeout)
+	assert.True(t, cfg.App.Debug)
+	assert.Equal(t, []string{"a", "b"}, cfg.App.Hosts)
+	assert.Equal(t, 15, *cfg.App.WriteTimeout)
+	assert.Equal(t, []string{override, srv.URL, "kv config/laugh-tale/", "env LT_*", "map"}, report.Sources)
+	assert.Equal(t, map[string]string{
+		"App.name":          "map",
+		"App.port":          "kv config/laugh-tale/",
+		"App.read_timeout":  "env LT_*",
+		"App.debug":         srv.URL,
+		"App.hosts":         override,
+		"App.write_timeout": "kv config/laugh-tale/",
+	}, report.Origins)
+}
+
+func TestConfigSourceFail(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var cfg sourceConfig
+	_, err := LoadConfig(ConfigOpts{
+		Config:  &cfg,
+		Sources: []Source{HTTPSource{URL: srv.URL}},
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+}
+
+func TestMemoryKV(t *testing.T) {
+	kv := &MemoryKV{}
+	kv.Put("a/b", "1")
+	kv.Put("c", "2")
+	kv.Delete("c")
+
+	got, err := kv.List("a/")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"a/b": "1"}, got)
+}
@@ -0,0 +1,228 @@
+package valkyrie
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+// DurationUnit is the unit of Duration values given as plain numbers,
+// e.g. expire_in: 40000 is 40000 seconds by default. It applies to every
+// Duration of the program, set it once at startup, before the first load;
+// changing it while configs are loaded or watched is a data race. Use
+// ConfigOpts.DurationUnit to give a config its own unit.
+var DurationUnit = time.Second
+
+var durationType = reflect.TypeOf(Duration(0))
+
+type (
+	// Duration is a time.Duration accepting "40s", "1h30m" or a number of DurationUnit,
+	// or of ConfigOpts.DurationUnit when loaded by Config.
+	Duration time.Duration
+
+	// ByteSize is a number of bytes accepting "512", "10KB", "10MiB" or "1.5GiB".
+	ByteSize uint64
+
+	// Location is a time zone name such as "Asia/Jakarta", checked when decoded.
+	Location string
+
+	// URL is an absolute URL, checked when decoded.
+	URL string
+
+	// LogLevel is a zerolog level name such as "debug" or "error".
+	LogLevel zerolog.Level
+)
+
+// Config types decode from YAML, JSON (through UnmarshalText or UnmarshalJSON)
+// and environment variables (through the cleanenv Setter interface).
+
+func (d Duration) Duration() time.Duration { return time.Duration(d) }
+
+func (d Duration) String() string { return time.Duration(d).String() }
+
+func (d Duration) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+
+func (d *Duration) UnmarshalText(b []byte) error { return d.SetValue(string(b)) }
+
+// UnmarshalJSON leaves d unchanged for null, like encoding/json does.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	return d.SetValue(strings.Trim(string(b), `"`))
+}
+
+func (d *Duration) UnmarshalYAML(n *yaml.Node) error { return d.SetValue(n.Value) }
+
+func (d *Duration) SetValue(s string) error {
+	s = strings.TrimSpace(s)
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		v, err := durationOf(f, DurationUnit)
+		if err != nil {
+			return fmt.Errorf("duration %q: %w", s, err)
+		}
+		*d = v
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// durationOf returns f units, strconv.ErrRange when it does not fit a Duration.
+func durationOf(f float64, unit time.Duration) (Duration, error) {
+	v := f * float64(unit)
+	// float64(math.MaxInt64) is 2^63, just out of range
+	if math.IsNaN(v) || v >= math.MaxInt64 || v < math.MinInt64 {
+		return 0, strconv.ErrRange
+	}
+	return Duration(v), nil
+}
+
+var byteUnits = map[string]uint64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+func (b ByteSize) String() string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	v, i := float64(b), 0
+	for v >= 1024 && i < len(units)-1 && math.Mod(v, 1024) == 0 {
+		v /= 1024
+		i++
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64) + units[i]
+}
+
+func (b ByteSize) MarshalText() ([]byte, error) { return []byte(b.String()), nil }
+
+func (b *ByteSize) UnmarshalText(p []byte) error { return b.SetValue(string(p)) }
+
+// UnmarshalJSON leaves b unchanged for null, like encoding/json does.
+func (b *ByteSize) UnmarshalJSON(p []byte) error {
+	if string(p) == "null" {
+		return nil
+	}
+	return b.SetValue(strings.Trim(string(p), `"`))
+}
+
+func (b *ByteSize) UnmarshalYAML(n *yaml.Node) error { return b.SetValue(n.Value) }
+
+func (b *ByteSize) SetValue(s string) error {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	unit, ok := byteUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return fmt.Errorf("invalid byte size %q", s)
+	}
+	f, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return fmt.Errorf("invalid byte size %q", s)
+	}
+	v := f * float64(unit)
+	// float64(math.MaxUint64) is 2^64, just out of range
+	if v >= math.MaxUint64 {
+		return fmt.Errorf("byte size %q: %w", s, strconv.ErrRange)
+	}
+	*b = ByteSize(v)
+	return nil
+}
+
+var locations sync.Map
+
+// Location returns the time zone, UTC when it is empty or unknown.
+func (l Location) Location() *time.Location {
+	if loc, ok := locations.Load(l); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(string(l))
+	if err != nil {
+		return time.UTC
+	}
+	locations.Store(l, loc)
+	return loc
+}
+
+func (l *Location) UnmarshalText(b []byte) error { return l.SetValue(string(b)) }
+
+func (l *Location) UnmarshalYAML(n *yaml.Node) error { return l.SetValue(n.Value) }
+
+func (l *Location) SetValue(s string) error {
+	loc, err := time.LoadLocation(s)
+	if err != nil {
+		return err
+	}
+	locations.Store(Location(s), loc)
+	*l = Location(s)
+	return nil
+}
+
+// URL returns the parsed URL, nil when it is empty.
+func (u URL) URL() *url.URL {
+	if u == "" {
+		return nil
+	}
+	v, _ := url.Parse(string(u))
+	return v
+}
+
+func (u *URL) UnmarshalText(b []byte) error { return u.SetValue(string(b)) }
+
+func (u *URL) UnmarshalYAML(n *yaml.Node) error { return u.SetValue(n.Value) }
+
+func (u *URL) SetValue(s string) error {
+	if s != "" {
+		v, err := url.Parse(s)
+		if err != nil {
+			return err
+		}
+		if !v.IsAbs() || v.Host == "" {
+			return fmt.Errorf("url %q must be absolute", s)
+		}
+	}
+	*u = URL(s)
+	return nil
+}
+
+func (l LogLevel) Level() zerolog.Level { return zerolog.Level(l) }
+
+func (l LogLevel) String() string { return zerolog.Level(l).String() }
+
+func (l LogLevel) MarshalText() ([]byte, error) { return []byte(l.String()), nil }
+
+func (l *LogLevel) UnmarshalText(b []byte) error { return l.SetValue(string(b)) }
+
+func (l *LogLevel) UnmarshalYAML(n *yaml.Node) error { return l.SetValue(n.Value) }
+
+func (l *LogLevel) SetValue(s string) error {
+	lvl, err := zerolog.ParseLevel(strings.ToLower(strings.TrimSpace(s)))
+	if err != nil {
+		return err
+	}
+	*l = LogLevel(lvl)
+	return nil
+}
@@ -0,0 +1,186 @@
+package valkyrie
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type typesConfig struct {
+	Timeout  Duration `yaml:"timeout" json:"timeout" env:"APP_TIMEOUT"`
+	Expire   Duration `yaml:"expire" json:"expire"`
+	MaxBody  ByteSize `yaml:"max_body" json:"max_body" env:"APP_MAX_BODY"`
+	Timezone Location `yaml:"timezone" json:"timezone" env:"APP_TZ"`
+	Endpoint URL      `yaml:"endpoint" json:"endpoint"`
+	Level    LogLevel `yaml:"level" json:"level" env:"APP_LEVEL"`
+}
+
+func TestConfigTypes(t *testing.T) {
+	const src = `{"timeout": "1h30m", "expire": 40, "max_body": "10MiB", "timezone": "Asia/Jakarta", "endpoint": "https://api.example.com/v1", "level": "debug"}`
+	check := func(t *testing.T, cfg typesConfig) {
+		assert.Equal(t, 90*time.Minute, cfg.Timeout.Duration())
+		assert.Equal(t, 40*time.Second, cfg.Expire.Duration())
+		assert.Equal(t, ByteSize(10<<20), cfg.MaxBody)
+		assert.Equal(t, "Asia/Jakarta", cfg.Timezone.Location().String())
+		assert.Equal(t, "api.example.com", cfg.Endpoint.URL().Host)
+		assert.Equal(t, zerolog.DebugLevel, cfg.Level.Level())
+	}
+
+	t.Run("yaml", func(t *testing.T) {
+		var cfg typesConfig
+		assert.NoError(t, yaml.Unmarshal([]byte(src), &cfg))
+		check(t, cfg)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var cfg typesConfig
+		assert.NoError(t, json.Unmarshal([]byte(src), &cfg))
+		check(t, cfg)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		dir := t.TempDir()
+		writeConfig(t, dir, "app.yaml", "expire: 40\nendpoint: https://api.example.com/v1\n")
+		t.Setenv("APP_TIMEOUT", "1h30m")
+		t.Setenv("APP_MAX_BODY", "10 MiB")
+		t.Setenv("APP_TZ", "Asia/Jakarta")
+		t.Setenv("APP_LEVEL", "DEBUG")
+
+		var cfg typesConfig
+		assert.NoError(t, Config(ConfigOpts{Config: &cfg, Filenames: []string{"app.yaml"}, Paths: []string{dir}}))
+		check(t, cfg)
+	})
+
+	t.Run("marshal", func(t *testing.T) {
+		var cfg typesConfig
+		assert.NoError(t, yaml.Unmarshal([]byte(src), &cfg))
+		b, err := json.Marshal(cfg)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"timeout": "1h30m0s", "expire": "40s", "max_body": "10MiB", "timezone": "Asia/Jakarta", "endpoint": "https://api.example.com/v1", "level": "debug"}`, string(b))
+	})
+}
+
+func TestConfigTypesInvalid(t *testing.T) {
+	tt := []string{
+		`timeout: soon`,
+		`max_body: 10XB`,
+		`timezone: Mars/Olympus`,
+		`endpoint: /relative`,
+		`level: loud`,
+		`timeout: 1e30`,
+		`timeout: NaN`,
+		`max_body: 99999999TiB`,
+	}
+	for _, src := range tt {
+		var cfg typesConfig
+		assert.Error(t, yaml.Unmarshal([]byte(src), &cfg), src)
+	}
+
+	var cfg typesConfig
+	err := json.Unmarshal([]byte(`{"timeout": 1e30}`), &cfg)
+	assert.True(t, errors.Is(err, strconv.ErrRange), err)
+}
+
+func TestConfigTypesNull(t *testing.T) {
+	cfg := typesConfig{Timeout: Duration(time.Minute), MaxBody: 512}
+	assert.NoError(t, json.Unmarshal([]byte(`{"timeout": null, "max_body": null}`), &cfg))
+	assert.Equal(t, time.Minute, cfg.Timeout.Duration())
+	assert.Equal(t, ByteSize(512), cfg.MaxBody)
+}
+
+func TestConfigDurationUnit(t *testing.T) {
+	type unitConfig struct {
+		Timeout Duration  `yaml:"timeout"`
+		Retry   *Duration `yaml:"retry"`
+		Named   Duration  `yaml:"named"`
+		Idle    Duration  `yaml:"idle" env-default:"30"`
+		Poll    Duration  `yaml:"poll" env:"UNIT_POLL"`
+		Grace   Duration  `yaml:"grace"`
+		Drain   Duration  `yaml:"drain"`
+	}
+	dir := t.TempDir()
+	writeConfig(t, dir, "app.yaml", "timeout: 1500\nretry: 2\nnamed: 40s\ngrace: 1\n")
+	t.Setenv("UNIT_POLL", "250")
+
+	load := func(unit time.Duration) (*unitConfig, error) {
+		cfg := &unitConfig{}
+		fs := flag.NewFlagSet("app", flag.ContinueOnError)
+		flags := BindConfigFlags(fs, cfg)
+		if err := fs.Parse([]string{"--drain=5"}); err != nil {
+			return nil, err
+		}
+		return cfg, Config(ConfigOpts{
+			Config:       cfg,
+			Filenames:    []string{"app.yaml"},
+			Paths:        []string{dir},
+			Sources:      []Source{MapSource{Label: "defaults", Values: map[string]interface{}{"grace": 3}}},
+			Flags:        flags,
+			DurationUnit: unit,
+		})
+	}
+
+	// two configs of one process read plain numbers in their own unit
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	units := make(map[time.Duration]*unitConfig)
+	for _, unit := range []time.Duration{time.Millisecond, time.Second, 0} {
+		wg.Add(1)
+		go func(unit time.Duration) {
+			defer wg.Done()
+			cfg, err := load(unit)
+			assert.NoError(t, err)
+			mtx.Lock()
+			units[unit] = cfg
+			mtx.Unlock()
+		}(unit)
+	}
+	wg.Wait()
+
+	ms := units[time.Millisecond]
+	assert.Equal(t, 1500*time.Millisecond, ms.Timeout.Duration())
+	assert.Equal(t, 2*time.Millisecond, ms.Retry.Duration())
+	assert.Equal(t, 40*time.Second, ms.Named.Duration())
+	assert.Equal(t, 30*time.Millisecond, ms.Idle.Duration())
+	assert.Equal(t, 250*time.Millisecond, ms.Poll.Duration())
+	assert.Equal(t, 3*time.Millisecond, ms.Grace.Duration())
+	assert.Equal(t, 5*time.Millisecond, ms.Drain.Duration())
+
+	for _, cfg := range []*unitConfig{units[time.Second], units[0]} {
+		assert.Equal(t, 1500*time.Second, cfg.Timeout.Duration())
+		assert.Equal(t, 2*time.Second, cfg.Retry.Duration())
+		assert.Equal(t, 30*time.Second, cfg.Idle.Duration())
+		assert.Equal(t, 250*time.Second, cfg.Poll.Duration())
+		assert.Equal(t, 3*time.Second, cfg.Grace.Duration())
+		assert.Equal(t, 5*time.Second, cfg.Drain.Duration())
+	}
+
+	_, err := load(time.Hour * 1e6)
+	assert.True(t, errors.Is(err, strconv.ErrRange), err)
+}
+
+func TestByteSize(t *testing.T) {
+	tt := []struct {
+		in   string
+		want ByteSize
+		str  string
+	}{
+		{"512", 512, "512B"},
+		{"10KB", 10000, "10000B"},
+		{"1.5GiB", 3 << 29, "1536MiB"},
+		{"2 tib", 2 << 40, "2TiB"},
+	}
+	for _, c := range tt {
+		var b ByteSize
+		assert.NoError(t, b.SetValue(c.in))
+		assert.Equal(t, c.want, b)
+		assert.Equal(t, c.str, b.String())
+	}
+}
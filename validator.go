@@ -1,9 +1,13 @@
 package valkyrie
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -11,117 +15,384 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
-type ErrorValidator struct {
-	Type    string `json:"error_type,omitempty"`
-	Tag     string `json:"error_tag,omitempty"`
-	Field   string `json:"error_field,omitempty"`
-	Value   string `json:"error_value,omitempty"`
-	Message string `json:"error_message,omitempty"`
-}
-
-func Validate(s interface{}) (errors []ErrorValidator) {
-	validate := validator.New()
-	_ = validate.RegisterValidation("date", DateValidation)
-	_ = validate.RegisterValidation("datetime", DatetimeValidation)
-	_ = validate.RegisterValidation("daterange", DateRangeValidation)
-	_ = validate.RegisterValidation("enum", ParseTags)
-	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
-		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
-		if name == "-" {
-			return ""
-		}
-		return name
-	})
-
-	if err := validate.Struct(s); err != nil {
-		for _, err := range err.(validator.ValidationErrors) {
-			errors = append(errors, ErrorValidator{
-				Tag:     err.Tag(),
-				Value:   fmt.Sprintf("%v", err.Value()),
-				Field:   err.Field(),
-				Type:    err.Type().String(),
-				Message: fmt.Sprintf("Invalid Type %v for input %s", err.Value(), err.Field()),
-			})
-		}
-		return errors
+// enumParamsRegex splits the enum tag parameters, 'quoted values' are kept together.
+var enumParamsRegex = regexp.MustCompile(`'[^']*'|\S+`)
+
+type (
+	// ErrorValidator describes a failed rule. Path locates the field from the validated
+	// value by json names, e.g. items[3].address.city, and Pointer is its JSON Pointer
+	// /items/3/address/city, while Field is the name of the field alone.
+	ErrorValidator struct {
+		Type    string `json:"error_type,omitempty"`
+		Tag     string `json:"error_tag,omitempty"`
+		Field   string `json:"error_field,omitempty"`
+		Path    string `json:"error_path,omitempty"`
+		Pointer string `json:"error_pointer,omitempty"`
+		Value   string `json:"error_value,omitempty"`
+		Param   string `json:"error_param,omitempty"`
+		Message string `json:"error_message,omitempty"`
+
+		// err is the error of a failed lookup, see ContextFunc
+		err error
+	}
+
+	// Validator checks the validate tags of structs with the custom rules registered.
+	// Build it once and share it, the parsed struct tags are cached per Validator.
+	// Registration is not safe for concurrent use, register before validating.
+	Validator struct {
+		validate      *validator.Validate
+		locale        string
+		lookups       map[string]Lookup
+		lookupTimeout time.Duration
+		ctxTags       map[string]bool
+		dates         dateRules
+	}
+
+	// ValidatorOption configures a Validator.
+	ValidatorOption func(*Validator)
+
+	// ValidationErrors lists the failed rules of a value, nil when it is valid. It is not an
+	// error, so an empty result never reads as a non-nil error, Err converts it into one.
+	ValidationErrors []ErrorValidator
+
+	// ValidationError is the error of failed rules returned by ValidationErrors.Err.
+	ValidationError struct {
+		Errors ValidationErrors
+	}
+)
+
+// NotStruct is matched by the Err of the ValidationErrors of a nil or non-struct value.
+var NotStruct = errors.New("must pass a struct or a pointer to a struct")
+
+// notStructTag is the Tag of the ErrorValidator reporting NotStruct.
+const notStructTag = "not_struct"
+
+// DefaultValidator is used by Validate, field names are reported by their json tag.
+var DefaultValidator = NewValidator()
+
+// WithTagNameFunc reports field names with fn instead of the json tag.
+func WithTagNameFunc(fn validator.TagNameFunc) ValidatorOption {
+	return func(v *Validator) {
+		v.validate.RegisterTagNameFunc(fn)
 	}
-	return nil
 }
 
-func DateValidation(fl validator.FieldLevel) bool {
-	if _, err := time.Parse("2006-01-02", fl.Field().String()); err != nil {
-		return false
+// WithDefaultLocale renders messages in locale instead of DefaultLocale.
+func WithDefaultLocale(locale string) ValidatorOption {
+	return func(v *Validator) {
+		v.locale = locale
 	}
-	return true
 }
 
-func DatetimeValidation(fl validator.FieldLevel) bool {
-	if _, err := time.Parse(time.RFC3339, fl.Field().String()); err != nil {
-		return false
+// WithValidation registers a custom tag, see Validator.RegisterValidation.
+func WithValidation(tag string, fn validator.Func) ValidatorOption {
+	return func(v *Validator) {
+		_ = v.RegisterValidation(tag, fn)
 	}
-	return true
 }
 
-func DateRangeValidation(fl validator.FieldLevel) bool {
+// WithAlias registers an alias, see Validator.RegisterAlias.
+func WithAlias(alias, tags string) ValidatorOption {
+	return func(v *Validator) {
+		v.RegisterAlias(alias, tags)
+	}
+}
 
-	var date = fl.Field().String()
-	var minDate = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
-	var maxDate = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+// NewValidator returns a Validator with the date rules registered, see dateTags, the
+// Indonesian rules, see indonesianTags, the enum and enum_strict tags, along with
+// exists and unique backed by the registered lookups.
+func NewValidator(opts ...ValidatorOption) *Validator {
+	v := &Validator{
+		validate:      validator.New(),
+		lookups:       make(map[string]Lookup),
+		lookupTimeout: DefaultLookupTimeout,
+		ctxTags:       make(map[string]bool),
+		dates:         dateRules{loc: time.UTC, layouts: defaultDateLayouts},
+	}
+	for tag, rule := range dateTags {
+		rule := rule
+		_ = v.validate.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+			return rule(v.dates, fl)
+		})
+	}
+	for tag, fn := range indonesianTags {
+		_ = v.validate.RegisterValidation(tag, fn)
+	}
+	_ = v.validate.RegisterValidation("enum", ParseTags)
+	_ = v.validate.RegisterValidation("enum_strict", EnumStrictValidation)
+	_ = v.RegisterValidationCtx("exists", v.lookupRule(true))
+	_ = v.RegisterValidationCtx("unique", v.lookupRule(false))
+	v.validate.RegisterTagNameFunc(jsonTagName)
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// RegisterValidation adds a custom tag or replaces an existing one.
+func (v *Validator) RegisterValidation(tag string, fn validator.Func) error {
+	return v.validate.RegisterValidation(tag, fn)
+}
 
-	datetime, err := time.Parse("2006-01-02", date)
-	if err != nil {
-		return false
+// RegisterAlias adds a tag expanding to tags, e.g. "iscolor" for "hexcolor|rgb|rgba".
+func (v *Validator) RegisterAlias(alias, tags string) {
+	v.validate.RegisterAlias(alias, tags)
+}
+
+// RegisterStructValidation adds a struct level rule for the types of the given values,
+// fn reports errors with sl.ReportError.
+func (v *Validator) RegisterStructValidation(fn validator.StructLevelFunc, types ...interface{}) {
+	v.validate.RegisterStructValidation(fn, types...)
+}
+
+// Validate checks the validate tags of s, see Translate for messages in other locales.
+// A nil or non-struct s is reported as a single error matching NotStruct.
+func (v *Validator) Validate(s interface{}) ValidationErrors {
+	return v.ValidateCtx(context.Background(), s)
+}
+
+// ValidateCtx works like Validate, ctx is given to the context-aware rules
+// and selects the message locale when it carries one, see WithLocale.
+func (v *Validator) ValidateCtx(ctx context.Context, s interface{}) ValidationErrors {
+	locale := v.locale
+	if l, ok := ctx.Value(localeKey{}).(string); ok {
+		locale = l
 	}
-	if datetime.Before(minDate) || datetime.After(maxDate) {
-		return false
+	state := &lookupState{}
+	err := v.validate.StructCtx(context.WithValue(ctx, lookupStateKey{}, state), s)
+	if err == nil {
+		return nil
 	}
-	return true
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		errs := toErrorValidators(verrs, reflect.TypeOf(s), locale)
+		v.attachLookupFailures(errs, verrs, state, locale)
+		return errs
+	}
+	ev := ErrorValidator{Tag: notStructTag, Type: fmt.Sprintf("%T", s), Value: fmt.Sprintf("%T", s)}
+	ev.Message = errorMessage(locale, ev)
+	return ValidationErrors{ev}
+}
+
+// Validate checks the validate tags of s with DefaultValidator.
+func Validate(s interface{}) ValidationErrors {
+	return DefaultValidator.Validate(s)
+}
+
+// ValidateVar checks value against tag, e.g. ValidateVar(email, "required,email").
+func (v *Validator) ValidateVar(value interface{}, tag string) ValidationErrors {
+	return v.validateVar(value, tag, "")
+}
+
+// ValidateMap checks the values of data against rules keyed by their path, values of
+// decoded JSON are addressed like items[0].sku or items.0.sku, missing values are nil.
+func (v *Validator) ValidateMap(data map[string]interface{}, rules map[string]string) ValidationErrors {
+	paths := make([]string, 0, len(rules))
+	for path := range rules {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var errs ValidationErrors
+	for _, path := range paths {
+		errs = append(errs, v.validateVar(mapValue(data, path), rules[path], path)...)
+	}
+	return errs
+}
+
+// ValidateVar checks value against tag with DefaultValidator.
+func ValidateVar(value interface{}, tag string) ValidationErrors {
+	return DefaultValidator.ValidateVar(value, tag)
 }
 
-func ParseDate(dtStr string) time.Time {
-	date, err := time.Parse("2006-01-02", dtStr)
-	if err != nil {
-		return time.Time{}
+// ValidateMap checks data against rules with DefaultValidator.
+func ValidateMap(data map[string]interface{}, rules map[string]string) ValidationErrors {
+	return DefaultValidator.ValidateMap(data, rules)
+}
+
+// validateVar checks value found at path, the field name is the last segment of path.
+func (v *Validator) validateVar(value interface{}, tag, path string) ValidationErrors {
+	state := &lookupState{}
+	var verrs validator.ValidationErrors
+	if !errors.As(v.validate.VarCtx(context.WithValue(context.Background(), lookupStateKey{}, state), value, tag), &verrs) {
+		return nil
+	}
+	field := path
+	if tokens := pathTokens(path); len(tokens) > 0 {
+		field = tokens[len(tokens)-1]
 	}
-	return date
+	errs := make(ValidationErrors, 0, len(verrs))
+	for _, err := range verrs {
+		// dive reports the element index as the namespace, e.g. [1]
+		errs = append(errs, toErrorValidator(err, field+err.Namespace(), path+err.Namespace(), v.locale))
+	}
+	v.attachLookupFailures(errs, verrs, state, v.locale)
+	return errs
 }
 
-func ParseDatetime(dtStr string) time.Time {
-	dateTime, err := time.Parse(time.RFC3339, dtStr)
-	if err != nil {
-		return time.Time{}
+// mapValue returns the value at path within nested maps and slices, nil when it is missing.
+func mapValue(data map[string]interface{}, path string) interface{} {
+	var cur interface{} = data
+	for _, token := range pathTokens(path) {
+		switch c := cur.(type) {
+		case map[string]interface{}:
+			cur = c[token]
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil
+			}
+			cur = c[i]
+		default:
+			return nil
+		}
 	}
-	return dateTime
+	return cur
 }
-func ParseTags(fl validator.FieldLevel) bool {
-	splitParamsRegex := regexp.MustCompile(`'[^']*'|\S+`)
-	params := fl.Param()
-	s := splitParamsRegex.FindAllString(params, -1)
-	values := make([]string, 0)
-	for i := 0; i < len(s); i++ {
-		p := strings.Replace(s[i], "'", "", -1)
-		p = strings.Replace(p, "[", "", -1)
-		p = strings.Replace(p, "]", "", -1)
-		values = append(values, strings.Split(p, " ")...)
+
+func (e ErrorValidator) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the error of a failed lookup.
+func (e ErrorValidator) Unwrap() error {
+	return e.err
+}
+
+// Err returns the errors as an error, nil when there are none:
+//
+//	if err := valkyrie.Validate(order).Err(); err != nil {
+//		return fmt.Errorf("create order: %w", err)
+//	}
+//
+// errors.As(err, &verr) with verr a *ValidationError gets every error back.
+func (e ValidationErrors) Err() error {
+	if len(e) == 0 {
+		return nil
 	}
-	field := fl.Field()
-	var v string
-	switch field.Kind() {
-	case reflect.String:
-		v = strings.ToLower(field.String())
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		v = strconv.FormatInt(field.Int(), 10)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		v = strconv.FormatUint(field.Uint(), 10)
-	default:
-		panic(fmt.Sprintf("Bad field type %T", field.Interface()))
+	return &ValidationError{Errors: e}
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, ev := range e.Errors {
+		msgs = append(msgs, ev.Message)
 	}
+	return strings.Join(msgs, "; ")
+}
 
-	for i := 0; i < len(values); i++ {
-		if values[i] == v {
+// Is reports whether target is NotStruct and the validated value was not a struct,
+// or whether target matches the error of a failed lookup.
+func (e *ValidationError) Is(target error) bool {
+	for _, ev := range e.Errors {
+		if target == NotStruct && ev.Tag == notStructTag {
+			return true
+		}
+		if ev.err != nil && errors.Is(ev.err, target) {
 			return true
 		}
 	}
-
 	return false
 }
+
+// ByField groups the errors by their Path.
+func (e ValidationErrors) ByField() map[string][]ErrorValidator {
+	fields := make(map[string][]ErrorValidator)
+	for _, ev := range e {
+		fields[ev.Path] = append(fields[ev.Path], ev)
+	}
+	return fields
+}
+
+// MarshalJSON encodes the errors as an array, [] when there are none.
+func (e ValidationErrors) MarshalJSON() ([]byte, error) {
+	if e == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]ErrorValidator(e))
+}
+
+func jsonTagName(fld reflect.StructField) string {
+	name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// toErrorValidators converts errs of the struct type root with messages in locale,
+// the msg and validate_msg tags of its fields take precedence over the catalogue.
+func toErrorValidators(errs validator.ValidationErrors, root reflect.Type, locale string) (errors ValidationErrors) {
+	for _, err := range errs {
+		ev := toErrorValidator(err, err.Field(), errorPath(err.Namespace()), locale)
+		if tmpl, ok := tagMessage(root, err); ok {
+			ev.Message = renderMessage(tmpl, ev)
+		}
+		errors = append(errors, ev)
+	}
+	return errors
+}
+
+// toErrorValidator converts err with its message in locale, DefaultLocale when it is empty.
+func toErrorValidator(err validator.FieldError, field, path, locale string) ErrorValidator {
+	if locale == "" {
+		locale = DefaultLocale
+	}
+	ev := ErrorValidator{
+		Tag:     err.Tag(),
+		Value:   fmt.Sprintf("%v", err.Value()),
+		Field:   field,
+		Path:    path,
+		Pointer: jsonPointer(path),
+		Param:   err.Param(),
+		Type:    fmt.Sprintf("%v", err.Type()),
+	}
+	ev.Message = errorMessage(locale, ev)
+	return ev
+}
+
+// errorPath drops the root struct name from a namespace, Order.items[3].city becomes items[3].city.
+func errorPath(namespace string) string {
+	if i := strings.IndexAny(namespace, ".["); i >= 0 && namespace[i] == '.' {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// jsonPointer converts a path such as items[3].address.city to /items/3/address/city.
+func jsonPointer(path string) string {
+	escape := strings.NewReplacer("~", "~0", "/", "~1")
+	var b strings.Builder
+	for _, token := range pathTokens(path) {
+		b.WriteString("/" + escape.Replace(token))
+	}
+	return b.String()
+}
+
+// pathTokens splits a path such as items[3].address.city into items, 3, address and city.
+func pathTokens(path string) []string {
+	var tokens []string
+	for path != "" {
+		var token string
+		switch {
+		case path[0] == '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				token, path = path[1:], ""
+				break
+			}
+			token, path = path[1:end], path[end+1:]
+		case path[0] == '.':
+			path = path[1:]
+			continue
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			token, path = path[:end], path[end:]
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
@@ -1,9 +1,13 @@
 package valkyrie
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -32,7 +36,7 @@ func TestValidate(t *testing.T) {
 				CourierID:     17,
 				PaymentMethod: "ovo",
 			},
-			[]ErrorValidator(nil),
+			ValidationErrors(nil),
 		},
 		{
 			"Test Validate Email Fail",
@@ -44,13 +48,15 @@ func TestValidate(t *testing.T) {
 				CourierID:     17,
 				PaymentMethod: "ovo",
 			},
-			[]ErrorValidator{
+			ValidationErrors{
 				{
 					Field:   "email",
+					Path:    "email",
+					Pointer: "/email",
 					Value:   "nanang.jobs@gmail",
 					Tag:     "email",
 					Type:    "string",
-					Message: "Invalid Type nanang.jobs@gmail for input email",
+					Message: "email must be a valid email address",
 				},
 			},
 		},
@@ -64,13 +70,15 @@ func TestValidate(t *testing.T) {
 				CourierID:     17,
 				PaymentMethod: "gopay",
 			},
-			[]ErrorValidator{
+			ValidationErrors{
 				{
 					Tag:     "date",
 					Value:   "2019-09",
 					Field:   "today",
+					Path:    "today",
+					Pointer: "/today",
 					Type:    "string",
-					Message: "Invalid Type 2019-09 for input today",
+					Message: "today must be a date formatted as YYYY-MM-DD, e.g. 2006-01-02",
 				},
 			},
 		},
@@ -84,13 +92,15 @@ func TestValidate(t *testing.T) {
 				CourierID:     0,
 				PaymentMethod: "ovo",
 			},
-			[]ErrorValidator{
+			ValidationErrors{
 				{
 					Tag:     "required",
 					Value:   "0",
 					Field:   "courier_id",
+					Path:    "courier_id",
+					Pointer: "/courier_id",
 					Type:    "int",
-					Message: "Invalid Type 0 for input courier_id",
+					Message: "courier_id is required",
 				},
 			},
 		},
@@ -121,3 +131,186 @@ func TestParseDatetime(t *testing.T) {
 	dt = ParseDatetime("2019-09-01T16:18:22Z00:00")
 	assert.Equal(t, time.Time{}, dt)
 }
+
+type signupForm struct {
+	Username string `json:"username" validate:"username"`
+	Color    string `json:"color" validate:"omitempty,color"`
+	Password string `json:"password"`
+	Confirm  string `json:"confirm"`
+}
+
+func TestValidatorRegister(t *testing.T) {
+	v := NewValidator(
+		WithValidation("username", func(fl validator.FieldLevel) bool {
+			return len(fl.Field().String()) >= 3
+		}),
+		WithAlias("color", "hexcolor|rgb"),
+	)
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		f := sl.Current().Interface().(signupForm)
+		if f.Password != f.Confirm {
+			sl.ReportError(f.Confirm, "confirm", "Confirm", "eqfield", "password")
+		}
+	}, signupForm{})
+
+	assert.Nil(t, v.Validate(signupForm{Username: "luffy", Color: "#fff", Password: "a", Confirm: "a"}))
+
+	errs := v.Validate(signupForm{Username: "lu", Color: "blue", Password: "a", Confirm: "b"})
+	assert.Len(t, errs, 3)
+	assert.Equal(t, "username", errs[0].Field)
+	assert.Equal(t, "username", errs[0].Tag)
+	assert.Equal(t, "color", errs[1].Field)
+	assert.Equal(t, "color", errs[1].Tag)
+	assert.Equal(t, "confirm", errs[2].Field)
+	assert.Equal(t, "eqfield", errs[2].Tag)
+
+	// the custom rules are still registered
+	errs = v.Validate(DataTransferObject{Email: "a@b.co", Password: "x", Today: "2019-09",
+		CreateDate: "2019-09-01T16:18:22+00:00", CourierID: 2, PaymentMethod: "ovo"})
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "date", errs[0].Tag)
+}
+
+var benchmarkDTO = DataTransferObject{
+	Email:         "nanang.jobs@gmail.com",
+	Password:      "sekret",
+	Today:         "2019-09-01",
+	CreateDate:    "2019-09-01T16:18:22+00:00",
+	CourierID:     17,
+	PaymentMethod: "ovo",
+}
+
+func BenchmarkValidate(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = Validate(benchmarkDTO)
+	}
+}
+
+// BenchmarkValidateNewValidator builds a validator per call like Validate used to.
+func BenchmarkValidateNewValidator(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = NewValidator().Validate(benchmarkDTO)
+	}
+}
+
+type (
+	address struct {
+		City string `json:"city" validate:"required"`
+	}
+	lineItem struct {
+		SKU     string  `json:"sku" validate:"required"`
+		Address address `json:"address"`
+	}
+	shipment struct {
+		Items  []lineItem         `json:"items" validate:"dive"`
+		Labels map[string]string  `json:"labels" validate:"dive,required"`
+		Meta   map[string]address `json:"meta" validate:"dive"`
+	}
+)
+
+func TestValidatePath(t *testing.T) {
+	errs := Validate(shipment{
+		Items:  []lineItem{{SKU: "a", Address: address{City: "Jakarta"}}, {Address: address{}}},
+		Labels: map[string]string{"a/b": ""},
+		Meta:   map[string]address{"home": {}},
+	})
+	paths := make(map[string]string)
+	for _, ev := range errs {
+		paths[ev.Path] = ev.Pointer
+	}
+	assert.Equal(t, map[string]string{
+		"items[1].sku":          "/items/1/sku",
+		"items[1].address.city": "/items/1/address/city",
+		"labels[a/b]":           "/labels/a~1b",
+		"meta[home].city":       "/meta/home/city",
+	}, paths)
+	assert.Equal(t, "city", errs[1].Field)
+}
+
+func TestValidationErrors(t *testing.T) {
+	errs := Validate(DataTransferObject{Email: "nanang.jobs@gmail", Password: "sekret", Today: "2019-09-01",
+		CreateDate: "2019-09-01T16:18:22+00:00", CourierID: 1, PaymentMethod: "ovo"})
+	err := errs.Err()
+	assert.EqualError(t, err, "email must be a valid email address; courier_id must be greater than 1")
+	assert.False(t, errors.Is(err, NotStruct))
+
+	var verr *ValidationError
+	assert.True(t, errors.As(fmt.Errorf("create order: %w", err), &verr))
+	assert.Equal(t, errs, verr.Errors)
+
+	// a valid value is a nil error, not a typed nil
+	assert.NoError(t, Validate(benchmarkDTO).Err())
+	assert.NoError(t, ValidateVar("a@b.co", "email").Err())
+
+	fields := errs.ByField()
+	assert.Len(t, fields, 2)
+	assert.Equal(t, "gt", fields["courier_id"][0].Tag)
+
+	b, err := json.Marshal(errs)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `[{"error_type":"string","error_tag":"email","error_field":"email"`)
+	b, err = json.Marshal(Validate(benchmarkDTO))
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", string(b))
+}
+
+func TestValidateNotStruct(t *testing.T) {
+	var dto *DataTransferObject
+	for _, in := range []interface{}{nil, dto, "string", 1, time.Now()} {
+		errs := Validate(in)
+		assert.Len(t, errs, 1)
+		assert.True(t, errors.Is(errs.Err(), NotStruct), "%T", in)
+	}
+	assert.EqualError(t, Validate(nil).Err(), "cannot validate <nil>, it must be a struct or a pointer to a struct")
+	assert.Nil(t, Validate(&benchmarkDTO))
+}
+
+func TestValidateVar(t *testing.T) {
+	assert.Nil(t, ValidateVar("nanang.jobs@gmail.com", "required,email"))
+	assert.Nil(t, ValidateVar("2019-09-01", "date"))
+
+	errs := ValidateVar("gopay-later", "enum=ovo gopay")
+	assert.Equal(t, ValidationErrors{{
+		Tag: "enum", Value: "gopay-later", Param: "ovo gopay", Type: "string",
+		Message: "value must be one of: ovo, gopay",
+	}}, errs)
+
+	errs = ValidateVar(nil, "required")
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "<nil>", errs[0].Type)
+}
+
+func TestValidateMap(t *testing.T) {
+	var data map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"email": "nanang.jobs@gmail",
+		"today": "2019-09-01",
+		"courier_id": 1,
+		"address": {"city": ""},
+		"items": [{"sku": "a"}, {"sku": ""}],
+		"tags": ["a", ""]
+	}`), &data))
+
+	errs := ValidateMap(data, map[string]string{
+		"email":        "required,email",
+		"password":     "required",
+		"today":        "date",
+		"courier_id":   "required,gt=1",
+		"address.city": "required",
+		"items[1].sku": "required",
+		"items.0.sku":  "required",
+		"tags":         "dive,min=1",
+	})
+	assert.Len(t, errs, 6)
+	fields := errs.ByField()
+	assert.Equal(t, "address.city", fields["address.city"][0].Path)
+	assert.Equal(t, "/address/city", fields["address.city"][0].Pointer)
+	assert.Equal(t, "city", fields["address.city"][0].Field)
+	assert.Equal(t, "courier_id must be greater than 1", fields["courier_id"][0].Message)
+	assert.Equal(t, "email must be a valid email address", fields["email"][0].Message)
+	assert.Equal(t, "/items/1/sku", fields["items[1].sku"][0].Pointer)
+	assert.Equal(t, "password is required", fields["password"][0].Message)
+	assert.Equal(t, "/tags/1", fields["tags[1]"][0].Pointer)
+}
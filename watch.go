@@ -0,0 +1,208 @@
+package valkyrie
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultWatchInterval is the polling interval of WatchConfig when none is given.
+var DefaultWatchInterval = 5 * time.Second
+
+type (
+	// ConfigChangeFunc is called with the previous and the reloaded config.
+	ConfigChangeFunc func(old, new interface{})
+
+	// ConfigWatcher keeps the configuration described by ConfigOpts up to date
+	// by polling its files and Sources and swapping in a freshly parsed struct on change.
+	ConfigWatcher struct {
+		opts     ConfigOpts
+		typ      reflect.Type
+		interval time.Duration
+		current  atomic.Value
+
+		// reloadMtx runs one reload at a time, mtx guards the fields below
+		reloadMtx sync.Mutex
+		mtx       sync.Mutex
+		env       string
+		includes  []string
+		stamps    map[string]fileStamp
+		subs      []ConfigChangeFunc
+		onError   []func(error)
+	}
+
+	fileStamp struct {
+		modTime time.Time
+		size    int64
+	}
+)
+
+// WatchConfig loads opts.Config like Config and returns a watcher polling
+// the config files every interval once Run is called, DefaultWatchInterval when
+// interval is not positive. opts.Sources cannot be watched, they are read again
+// on every interval and subscribers are only called when the config differs.
+// The loaded struct must be read through Current, opts.Config only holds the first load.
+func WatchConfig(opts ConfigOpts, interval time.Duration) (*ConfigWatcher, error) {
+	v := reflect.ValueOf(opts.Config)
+	if v.Kind() != reflect.Ptr {
+		return nil, NotPointer
+	}
+	if v.IsNil() {
+		return nil, NilPointer
+	}
+	report, err := LoadConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	w := &ConfigWatcher{
+		opts:     opts,
+		typ:      v.Elem().Type(),
+		interval: interval,
+	}
+	w.current.Store(opts.Config)
+	w.env, w.includes = report.Env, report.Includes
+	w.stamps = w.stat(w.env, w.includes)
+	return w, nil
+}
+
+// Current returns the latest successfully loaded config, a pointer of the opts.Config type.
+func (w *ConfigWatcher) Current() interface{} {
+	return w.current.Load()
+}
+
+// Subscribe registers fn to be called after every successful reload.
+func (w *ConfigWatcher) Subscribe(fn ConfigChangeFunc) {
+	w.mtx.Lock()
+	w.subs = append(w.subs, fn)
+	w.mtx.Unlock()
+}
+
+// OnError registers fn to be called when a reload fails,
+// the previous config stays in place.
+func (w *ConfigWatcher) OnError(fn func(error)) {
+	w.mtx.Lock()
+	w.onError = append(w.onError, fn)
+	w.mtx.Unlock()
+}
+
+// Run polls the config files until ctx is done and reloads when any of them changes,
+// or on every interval when there are Sources.
+func (w *ConfigWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			switch {
+			case w.changed():
+				_ = w.Reload()
+			case len(w.opts.Sources) > 0:
+				_ = w.reload(false)
+			}
+		}
+	}
+}
+
+// Reload parses the config files into a fresh struct, validates it and swaps it in.
+// A failed reload keeps the previous config and is reported to the OnError handlers.
+func (w *ConfigWatcher) Reload() error {
+	return w.reload(true)
+}
+
+// reload loads the config without holding mtx, so a slow Source does not block
+// Subscribe, OnError and changed. Unless always is set, a config equal to the
+// current one is dropped without notifying the subscribers.
+func (w *ConfigWatcher) reload(always bool) error {
+	w.reloadMtx.Lock()
+	defer w.reloadMtx.Unlock()
+
+	opts := w.opts
+	opts.Config = reflect.New(w.typ).Interface()
+	report, err := LoadConfig(opts)
+	old := w.current.Load()
+	if err == nil && !always && reflect.DeepEqual(old, opts.Config) {
+		return nil
+	}
+
+	w.mtx.Lock()
+	if err == nil {
+		w.current.Store(opts.Config)
+		w.env, w.includes = report.Env, report.Includes
+	}
+	env, includes := w.env, w.includes
+	w.mtx.Unlock()
+
+	// remember the failed state too, so Run retries on the next change only
+	stamps := w.stat(env, includes)
+	w.mtx.Lock()
+	w.stamps = stamps
+	subs, onError := w.subs, w.onError
+	w.mtx.Unlock()
+
+	if err != nil {
+		err = fmt.Errorf("config reload: %w", err)
+		log.Error().Err(err).Msg("keeping previous config")
+		for _, fn := range onError {
+			fn(err)
+		}
+		return err
+	}
+	for _, fn := range subs {
+		fn(old, opts.Config)
+	}
+	return nil
+}
+
+func (w *ConfigWatcher) changed() bool {
+	w.mtx.Lock()
+	env, includes, stamps := w.env, w.includes, w.stamps
+	w.mtx.Unlock()
+	return !reflect.DeepEqual(stamps, w.stat(env, includes))
+}
+
+// stat records every candidate config and dotenv file and the included files,
+// missing files too, so files appearing in a search path are noticed.
+func (w *ConfigWatcher) stat(env string, includes []string) map[string]fileStamp {
+	paths := w.opts.Paths
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	names := append([]string{}, w.opts.Filenames...)
+	if len(w.opts.Dotenv) > 0 {
+		names = append(names, w.opts.Dotenv...)
+	} else {
+		names = append(names, DotenvFiles...)
+	}
+	if env != "" {
+		for _, f := range w.opts.Filenames {
+			names = append(names, overlayName(f, env))
+		}
+	}
+	files := append([]string{}, includes...)
+	for _, p := range paths {
+		for _, f := range names {
+			files = append(files, filepath.Join(p, f))
+		}
+	}
+	stamps := make(map[string]fileStamp)
+	for _, fp := range files {
+		if info, err := os.Stat(fp); err == nil {
+			stamps[fp] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		} else {
+			stamps[fp] = fileStamp{}
+		}
+	}
+	return stamps
+}
@@ -0,0 +1,190 @@
+package valkyrie
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type watchConfig struct {
+	App struct {
+		Name  string `yaml:"name" validate:"required"`
+		Level string `yaml:"level"`
+	} `yaml:"App"`
+}
+
+func touchConfig(t *testing.T, fp, content string, at time.Time) {
+	t.Helper()
+	assert.NoError(t, os.WriteFile(fp, []byte(content), 0o644))
+	assert.NoError(t, os.Chtimes(fp, at, at))
+}
+
+func TestWatchConfig(t *testing.T) {
+	dir := t.TempDir()
+	fp := writeConfig(t, dir, "app.yaml", "App:\n  name: laugh-tale\n  level: info\n")
+
+	w, err := WatchConfig(ConfigOpts{
+		Config:    &watchConfig{},
+		Filenames: []string{"app.yaml"},
+		Paths:     []string{dir},
+	}, 10*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, "info", w.Current().(*watchConfig).App.Level)
+
+	changes := make(chan [2]*watchConfig, 1)
+	w.Subscribe(func(old, new interface{}) {
+		changes <- [2]*watchConfig{old.(*watchConfig), new.(*watchConfig)}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	touchConfig(t, fp, "App:\n  name: laugh-tale\n  level: debug\n", time.Now().Add(time.Minute))
+	select {
+	case c := <-changes:
+		assert.Equal(t, "info", c[0].App.Level)
+		assert.Equal(t, "debug", c[1].App.Level)
+	case <-time.After(time.Second):
+		t.Fatal("config change not noticed")
+	}
+	assert.Equal(t, "debug", w.Current().(*watchConfig).App.Level)
+}
+
+func TestWatchConfigReloadFail(t *testing.T) {
+	dir := t.TempDir()
+	fp := writeConfig(t, dir, "app.yaml", "App:\n  name: laugh-tale\n")
+
+	w, err := WatchConfig(ConfigOpts{
+		Config:    &watchConfig{},
+		Filenames: []string{"app.yaml"},
+		Paths:     []string{dir},
+	}, time.Second)
+	assert.NoError(t, err)
+
+	var reported []error
+	w.OnError(func(err error) { reported = append(reported, err) })
+	w.Subscribe(func(_, _ interface{}) { t.Fatal("failed reload must not notify subscribers") })
+
+	touchConfig(t, fp, "App: [broken", time.Now().Add(time.Minute))
+	assert.Error(t, w.Reload())
+
+	touchConfig(t, fp, "App:\n  level: debug\n", time.Now().Add(2*time.Minute))
+	assert.Error(t, w.Reload())
+
+	assert.Len(t, reported, 2)
+	assert.Equal(t, "laugh-tale", w.Current().(*watchConfig).App.Name)
+}
+
+func TestWatchConfigNotPointer(t *testing.T) {
+	_, err := WatchConfig(ConfigOpts{Config: watchConfig{}}, time.Second)
+	assert.Equal(t, NotPointer, err)
+}
+
+func TestWatchConfigInterval(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "app.yaml", "App:\n  name: laugh-tale\n")
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		w, err := WatchConfig(ConfigOpts{
+			Config:    &watchConfig{},
+			Filenames: []string{"app.yaml"},
+			Paths:     []string{dir},
+		}, interval)
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultWatchInterval, w.interval)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.NotPanics(t, func() { w.Run(ctx) })
+	}
+}
+
+type funcSource func() (map[string]interface{}, error)
+
+func (f funcSource) Name() string { return "func" }
+
+func (f funcSource) Load() (map[string]interface{}, error) { return f() }
+
+func TestWatchConfigSlowSource(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "app.yaml", "App:\n  name: laugh-tale\n")
+
+	var slow int32
+	loading, release := make(chan struct{}), make(chan struct{})
+	src := funcSource(func() (map[string]interface{}, error) {
+		if atomic.LoadInt32(&slow) == 1 {
+			loading <- struct{}{}
+			<-release
+		}
+		return map[string]interface{}{"App": map[string]interface{}{"level": "info"}}, nil
+	})
+	w, err := WatchConfig(ConfigOpts{
+		Config:    &watchConfig{},
+		Filenames: []string{"app.yaml"},
+		Paths:     []string{dir},
+		Sources:   []Source{src},
+	}, time.Hour)
+	assert.NoError(t, err)
+
+	atomic.StoreInt32(&slow, 1)
+	done := make(chan error)
+	go func() { done <- w.Reload() }()
+	<-loading
+
+	// a slow source does not block the watcher
+	blocked := make(chan struct{})
+	go func() {
+		w.Subscribe(func(_, _ interface{}) {})
+		w.OnError(func(error) {})
+		w.changed()
+		close(blocked)
+	}()
+	select {
+	case <-blocked:
+	case <-time.After(time.Second):
+		t.Fatal("watcher blocked by a loading source")
+	}
+	close(release)
+	assert.NoError(t, <-done)
+}
+
+func TestWatchConfigSources(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "app.yaml", "App:\n  name: laugh-tale\n")
+
+	var level atomic.Value
+	level.Store("info")
+	src := funcSource(func() (map[string]interface{}, error) {
+		return map[string]interface{}{"App": map[string]interface{}{"level": level.Load()}}, nil
+	})
+	w, err := WatchConfig(ConfigOpts{
+		Config:    &watchConfig{},
+		Filenames: []string{"app.yaml"},
+		Paths:     []string{dir},
+		Sources:   []Source{src},
+	}, 10*time.Millisecond)
+	assert.NoError(t, err)
+
+	changes := make(chan string, 10)
+	w.Subscribe(func(_, new interface{}) { changes <- new.(*watchConfig).App.Level })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	// sources are polled, an unchanged config is not reported
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, changes, 0)
+
+	level.Store("debug")
+	select {
+	case got := <-changes:
+		assert.Equal(t, "debug", got)
+	case <-time.After(time.Second):
+		t.Fatal("source change not noticed")
+	}
+	assert.Equal(t, "debug", w.Current().(*watchConfig).App.Level)
+}